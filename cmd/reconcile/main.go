@@ -0,0 +1,129 @@
+// Command reconcile backfills users for Kratos identities that have no local user,
+// e.g. because their registration webhook failed or they registered before the service existed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	"github.com/HellUpa/taskmanager/internal/config"
+	"github.com/HellUpa/taskmanager/internal/db"
+	"github.com/HellUpa/taskmanager/internal/logger"
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	kratos "github.com/ory/kratos-client-go"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	pageSize := flag.Int64("page-size", 250, "number of identities requested from Kratos per page")
+	dryRun := flag.Bool("dry-run", false, "only report missing users, do not create them")
+
+	// Configure the application, this also parses the flags.
+	cfg := config.MustLoad()
+	log := logger.SetupLogger(cfg.Env)
+
+	postgresDB, err := db.NewPostgresDB(log, cfg.Database)
+	if err != nil {
+		log.Error("Failed to connect to database", logu.Err(err))
+		os.Exit(1)
+	}
+	defer postgresDB.Close()
+
+	// Reconciliation only creates users, so it needs no blob store.
+	taskManagerService := app.NewTaskManagerService(log, postgresDB, cfg.Tasks, nil, cfg.Attachments)
+
+	// Identities are listed through the Kratos admin API.
+	kratosConfig := kratos.NewConfiguration()
+	kratosConfig.Servers = kratos.ServerConfigurations{
+		{
+			URL: fmt.Sprintf("http://%v:4434", cfg.Auth.KratosIP),
+		},
+	}
+	kratosClient := kratos.NewAPIClient(kratosConfig)
+
+	if err := reconcile(context.Background(), log, kratosClient, taskManagerService, *pageSize, *dryRun); err != nil {
+		log.Error("Reconciliation failed", logu.Err(err))
+		os.Exit(1)
+	}
+}
+
+// reconcile pages through all Kratos identities and creates the users that are missing.
+func reconcile(ctx context.Context, log *slog.Logger, kratosClient *kratos.APIClient, tm *app.TaskManagerService, pageSize int64, dryRun bool) error {
+	var seen, missing, created int
+	pageToken := ""
+	for {
+		req := kratosClient.IdentityAPI.ListIdentities(ctx).PageSize(pageSize)
+		if pageToken != "" {
+			req = req.PageToken(pageToken)
+		}
+		identities, resp, err := req.Execute()
+		if err != nil {
+			return fmt.Errorf("failed to list Kratos identities: %w", err)
+		}
+		resp.Body.Close()
+
+		for _, identity := range identities {
+			seen++
+			user, err := tm.GetUserByKratosID(ctx, identity.Id)
+			if err != nil {
+				return err
+			}
+			if user != nil {
+				continue
+			}
+
+			missing++
+			if dryRun {
+				log.Info("Missing user", slog.String("kratosID", identity.Id))
+				continue
+			}
+			traits, _ := models.ParseIdentityTraits(identity.Traits)
+			user, isNew, err := tm.EnsureUser(ctx, identity.Id, traits)
+			if err != nil {
+				return fmt.Errorf("failed to create user for Kratos ID %s: %w", identity.Id, err)
+			}
+			if isNew {
+				created++
+				log.Info("User created", slog.String("kratosID", identity.Id), slog.String("userID", user.ID.String()))
+			}
+		}
+
+		pageToken = nextPageToken(resp)
+		if pageToken == "" || len(identities) == 0 {
+			break
+		}
+	}
+
+	log.Info("Reconciliation complete",
+		slog.Int("identities", seen), slog.Int("missing", missing), slog.Int("created", created), slog.Bool("dryRun", dryRun))
+	return nil
+}
+
+// nextPageToken returns the page token of the rel="next" link Kratos sends with paginated lists,
+// or "" on the last page.
+func nextPageToken(resp *http.Response) string {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			if !ok || !strings.Contains(params, `rel="next"`) {
+				continue
+			}
+			target = strings.Trim(strings.TrimSpace(target), "<>")
+			u, err := url.Parse(target)
+			if err != nil {
+				continue
+			}
+			return u.Query().Get("page_token")
+		}
+	}
+	return ""
+}
@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // IANA zones for user time zones, also in minimal images
 
 	"github.com/HellUpa/taskmanager/internal/app"
 	"github.com/HellUpa/taskmanager/internal/config"
@@ -17,6 +18,8 @@ import (
 	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
 	"github.com/HellUpa/taskmanager/internal/logger"
 	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/HellUpa/taskmanager/internal/storage"
 	"github.com/HellUpa/taskmanager/internal/telemetry"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -58,6 +61,10 @@ func main() {
 	if err != nil {
 		log.Error("Failed to create request latency histogram", logu.Err(err))
 	}
+	sessionCacheLookups, err := telemetry.CreateCounter(meter, "session_cache_lookups_total", "Kratos session cache lookups by result (hit or miss)")
+	if err != nil {
+		log.Error("Failed to create session cache counter", logu.Err(err))
+	}
 	log.Debug("Metrics initialization complete")
 
 	// Connect to PostgreSQL.
@@ -68,10 +75,28 @@ func main() {
 	defer postgresDB.Close()
 	log.Debug("Connected to PostgreSQL database")
 
+	// Attachment contents are kept in the configured blob store.
+	blobStore, err := storage.New(cfg.Attachments)
+	if err != nil {
+		log.Error("Failed to create blob store", logu.Err(err))
+		os.Exit(1)
+	}
+
 	// Create the TaskManager service.
-	taskManagerService := app.NewTaskManagerService(log, postgresDB)
+	taskManagerService := app.NewTaskManagerService(log, postgresDB, cfg.Tasks, blobStore, cfg.Attachments)
 	log.Debug("TaskManager service created")
 
+	// Purge expired idempotency keys in the background.
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	go taskManagerService.RunIdempotencySweeper(sweeperCtx)
+
+	// Remove expired uploads and the blobs of deleted attachments in the background.
+	go taskManagerService.RunAttachmentCleaner(sweeperCtx)
+
+	// Permanently delete tasks that have been in the trash past the retention in the background.
+	go taskManagerService.RunTrashPurger(sweeperCtx)
+
 	// Kratos Client Configuration
 	kratosConfig := kratos.NewConfiguration()
 	kratosConfig.Servers = kratos.ServerConfigurations{
@@ -82,6 +107,24 @@ func main() {
 	kratosClient := kratos.NewAPIClient(kratosConfig)
 	log.Debug("Kratos client configured", slog.String("kratos_ip", cfg.Auth.KratosIP))
 
+	// The admin API deletes identities of users deleting their account.
+	var kratosAdminClient *kratos.APIClient
+	if cfg.Auth.DeleteKratosIdentity {
+		kratosAdminConfig := kratos.NewConfiguration()
+		kratosAdminConfig.Servers = kratos.ServerConfigurations{
+			{
+				URL: fmt.Sprintf("http://%v:4434", cfg.Auth.KratosIP),
+			},
+		}
+		kratosAdminClient = kratos.NewAPIClient(kratosAdminConfig)
+	}
+
+	// Validate OAuth2 access tokens with Hydra, when configured.
+	introspector := middlewares.NewTokenIntrospector(cfg.Auth.HydraAdminURL, cfg.Auth.IntrospectionTimeout)
+
+	// Cache session verifications to spare a Kratos round-trip on every request.
+	sessionCache := middlewares.NewSessionCache(cfg.Auth.SessionCacheSize, cfg.Auth.SessionCacheTTL, cfg.Auth.SessionCacheNegativeTTL, sessionCacheLookups)
+
 	// Create a new Chi router.
 	r := chi.NewRouter()
 
@@ -90,20 +133,126 @@ func main() {
 	r.Use(middleware.RealIP)
 	r.Use(logger.NewMiddlewareLogger(log))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
 	r.Use(telemetry.HTTPRequestMetrics(requestCount, requestLatency))
 
+	// Requests are limited to a minute, except for the transfer of attachment contents,
+	// which get the longer transfer timeout instead.
+	requestTimeout := middleware.Timeout(60 * time.Second)
+	transferDeadline := middlewares.TransferDeadline(log, cfg.Attachments.TransferTimeout)
+
 	// Routes.
-	r.Post("/webhooks/kratos", handlers.KratosRegistrationWebhookHandler(taskManagerService))
+	// Kratos webhooks, only accepted from verified senders.
+	r.Group(func(r chi.Router) {
+		r.Use(requestTimeout)
+		r.Use(middlewares.WebhookAuthMiddleware(cfg.Auth.WebhookAPIKey, cfg.Auth.WebhookSecret, cfg.Auth.WebhookReplayWindow))
+		r.Post("/webhooks/kratos", handlers.KratosRegistrationWebhookHandler(taskManagerService))
+		r.Post("/webhooks/kratos/settings", handlers.KratosSettingsWebhookHandler(taskManagerService))
+		r.Post("/webhooks/kratos/identity-deleted", handlers.KratosIdentityDeletedWebhookHandler(taskManagerService, sessionCache))
+	})
 
 	// Routes that require authentication.
 	r.Group(func(r chi.Router) {
-		r.Use(middlewares.AuthMiddleware(kratosClient, taskManagerService, cfg.Auth.UI_IP))
-		r.Get("/tasks", handlers.ListTasksHandler(taskManagerService))
-		r.Post("/tasks", handlers.CreateTaskHandler(taskManagerService))
-		r.Get("/tasks/{id}", handlers.GetTaskHandler(taskManagerService))
-		r.Put("/tasks/{id}", handlers.UpdateTaskHandler(taskManagerService))
-		r.Delete("/tasks/{id}", handlers.DeleteTaskHandler(taskManagerService))
+		r.Use(middlewares.AuthMiddleware(kratosClient, introspector, taskManagerService, sessionCache, cfg.Auth))
+
+		// Personal access tokens and OAuth2 access tokens are limited to the routes their scopes cover.
+		// Attachment contents are transferred under the transfer deadline.
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireScopes(models.ScopeTasksRead, models.ScopeTasksWrite))
+			r.With(transferDeadline).Post("/tasks/{id}/attachments", handlers.UploadAttachmentHandler(taskManagerService))
+			r.With(transferDeadline).Get("/tasks/{id}/attachments/{attachmentID}/content", handlers.DownloadAttachmentHandler(taskManagerService))
+			r.With(transferDeadline).Patch("/tasks/{id}/attachments/uploads/{uploadID}", handlers.AppendUploadHandler(taskManagerService))
+		})
+
+		// All other scoped routes.
+		r.Group(func(r chi.Router) {
+			r.Use(requestTimeout)
+			r.Use(middlewares.RequireScopes(models.ScopeTasksRead, models.ScopeTasksWrite))
+			r.Get("/tasks", handlers.ListTasksHandler(taskManagerService))
+			r.With(middlewares.IdempotencyMiddleware(taskManagerService)).Post("/tasks", handlers.CreateTaskHandler(taskManagerService))
+			r.Get("/tasks/search", handlers.SearchTasksHandler(taskManagerService))
+			r.Get("/tasks/assigned", handlers.ListAssignedTasksHandler(taskManagerService))
+			r.Get("/tasks/shared-with-me", handlers.ListSharedTasksHandler(taskManagerService))
+			r.Get("/trash", handlers.ListTrashHandler(taskManagerService))
+			r.Delete("/trash/{id}", handlers.PurgeTaskHandler(taskManagerService))
+			r.Get("/tasks/{id}", handlers.GetTaskHandler(taskManagerService))
+			r.Put("/tasks/{id}", handlers.UpdateTaskHandler(taskManagerService))
+			r.Patch("/tasks/{id}", handlers.PatchTaskHandler(taskManagerService))
+			r.Delete("/tasks/{id}", handlers.DeleteTaskHandler(taskManagerService))
+			r.Post("/tasks/{id}/move", handlers.MoveTaskHandler(taskManagerService))
+			r.Post("/tasks/{id}/restore", handlers.RestoreTaskHandler(taskManagerService))
+			r.Get("/tasks/{id}/children", handlers.ListSubtasksHandler(taskManagerService))
+			r.Get("/tasks/{id}/tree", handlers.GetTaskTreeHandler(taskManagerService))
+			r.Put("/tasks/{id}/parent", handlers.SetTaskParentHandler(taskManagerService))
+			r.Get("/tasks/{id}/occurrences", handlers.PreviewOccurrencesHandler(taskManagerService))
+			r.Put("/tasks/{id}/labels/{labelID}", handlers.AttachTaskLabelHandler(taskManagerService))
+			r.Delete("/tasks/{id}/labels/{labelID}", handlers.DetachTaskLabelHandler(taskManagerService))
+			r.Put("/tasks/{id}/assignee", handlers.SetTaskAssigneeHandler(taskManagerService))
+			r.Get("/tasks/{id}/shares", handlers.ListTaskSharesHandler(taskManagerService))
+			r.Post("/tasks/{id}/shares", handlers.ShareTaskHandler(taskManagerService))
+			r.Delete("/tasks/{id}/shares/{userID}", handlers.UnshareTaskHandler(taskManagerService))
+			r.Get("/tasks/{id}/comments", handlers.ListCommentsHandler(taskManagerService))
+			r.Post("/tasks/{id}/comments", handlers.CreateCommentHandler(taskManagerService))
+			r.Patch("/tasks/{id}/comments/{commentID}", handlers.UpdateCommentHandler(taskManagerService))
+			r.Delete("/tasks/{id}/comments/{commentID}", handlers.DeleteCommentHandler(taskManagerService))
+			r.Get("/tasks/{id}/comments/{commentID}/history", handlers.ListCommentHistoryHandler(taskManagerService))
+			r.Get("/tasks/{id}/activity", handlers.GetTaskActivityHandler(taskManagerService))
+			r.Get("/tasks/{id}/history", handlers.GetTaskHistoryHandler(taskManagerService))
+			r.Post("/tasks/{id}/history/{version}/restore", handlers.RestoreTaskRevisionHandler(taskManagerService))
+			r.Get("/tasks/{id}/attachments", handlers.ListAttachmentsHandler(taskManagerService))
+			r.Get("/tasks/{id}/attachments/{attachmentID}", handlers.GetAttachmentHandler(taskManagerService))
+			r.Delete("/tasks/{id}/attachments/{attachmentID}", handlers.DeleteAttachmentHandler(taskManagerService))
+			r.Post("/tasks/{id}/attachments/uploads", handlers.CreateUploadHandler(taskManagerService))
+			r.Get("/tasks/{id}/attachments/uploads/{uploadID}", handlers.GetUploadHandler(taskManagerService))
+			r.Head("/tasks/{id}/attachments/uploads/{uploadID}", handlers.GetUploadHandler(taskManagerService))
+			r.Delete("/tasks/{id}/attachments/uploads/{uploadID}", handlers.AbortUploadHandler(taskManagerService))
+
+			r.Get("/labels", handlers.ListLabelsHandler(taskManagerService))
+			r.Post("/labels", handlers.CreateLabelHandler(taskManagerService))
+			r.Get("/labels/{id}", handlers.GetLabelHandler(taskManagerService))
+			r.Put("/labels/{id}", handlers.UpdateLabelHandler(taskManagerService))
+			r.Delete("/labels/{id}", handlers.DeleteLabelHandler(taskManagerService))
+
+			r.Get("/projects", handlers.ListProjectsHandler(taskManagerService))
+			r.Post("/projects", handlers.CreateProjectHandler(taskManagerService))
+			r.Get("/projects/{id}", handlers.GetProjectHandler(taskManagerService))
+			r.Put("/projects/{id}", handlers.UpdateProjectHandler(taskManagerService))
+			r.Delete("/projects/{id}", handlers.DeleteProjectHandler(taskManagerService))
+			r.Get("/projects/{id}/tasks", handlers.ListProjectTasksHandler(taskManagerService))
+			r.Post("/projects/{id}/archive", handlers.ArchiveProjectHandler(taskManagerService))
+			r.Post("/projects/{id}/unarchive", handlers.UnarchiveProjectHandler(taskManagerService))
+
+			r.Get("/workspaces", handlers.ListWorkspacesHandler(taskManagerService))
+			r.Post("/workspaces", handlers.CreateWorkspaceHandler(taskManagerService))
+			r.Get("/workspaces/{id}", handlers.GetWorkspaceHandler(taskManagerService))
+			r.Put("/workspaces/{id}", handlers.UpdateWorkspaceHandler(taskManagerService))
+			r.Delete("/workspaces/{id}", handlers.DeleteWorkspaceHandler(taskManagerService))
+			r.Get("/workspaces/{id}/members", handlers.ListWorkspaceMembersHandler(taskManagerService))
+			r.Put("/workspaces/{id}/members/{userID}", handlers.UpdateWorkspaceMemberHandler(taskManagerService))
+			r.Delete("/workspaces/{id}/members/{userID}", handlers.RemoveWorkspaceMemberHandler(taskManagerService))
+			r.Get("/workspaces/{id}/invitations", handlers.ListWorkspaceInvitationsHandler(taskManagerService))
+			r.Post("/workspaces/{id}/invitations", handlers.CreateWorkspaceInvitationHandler(taskManagerService))
+			r.Delete("/workspaces/{id}/invitations/{invitationID}", handlers.RevokeWorkspaceInvitationHandler(taskManagerService))
+
+			r.Get("/me", handlers.GetMeHandler(taskManagerService))
+			r.Put("/me", handlers.UpdateMeHandler(taskManagerService))
+			r.Get("/me/settings", handlers.GetSettingsHandler(taskManagerService))
+			r.Put("/me/settings", handlers.UpdateSettingsHandler(taskManagerService))
+			r.Get("/me/invitations", handlers.ListMyInvitationsHandler(taskManagerService))
+			r.Post("/me/invitations/{id}/accept", handlers.AnswerInvitationHandler(taskManagerService, true))
+			r.Post("/me/invitations/{id}/decline", handlers.AnswerInvitationHandler(taskManagerService, false))
+		})
+
+		// Sessions, tokens and the account itself can only be managed from a browser session, not with a token.
+		r.Group(func(r chi.Router) {
+			r.Use(requestTimeout)
+			r.Use(middlewares.RequireSession)
+			r.Post("/logout", handlers.LogoutHandler(taskManagerService, kratosClient, sessionCache))
+			r.Get("/me/export", handlers.ExportMeHandler(taskManagerService))
+			r.Delete("/me", handlers.DeleteMeHandler(taskManagerService, kratosAdminClient, sessionCache))
+			r.Get("/me/tokens", handlers.ListTokensHandler(taskManagerService))
+			r.Post("/me/tokens", handlers.CreateTokenHandler(taskManagerService))
+			r.Delete("/me/tokens/{id}", handlers.RevokeTokenHandler(taskManagerService))
+		})
 	})
 	log.Debug("Routes for base port configured")
 
@@ -161,6 +310,7 @@ func main() {
 
 	<-stop
 	log.Info("Shutting down server...")
+	stopSweeper()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
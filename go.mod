@@ -12,6 +12,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/ory/kratos-client-go v1.3.8
 	github.com/prometheus/client_golang v1.21.1
+	github.com/teambition/rrule-go v1.8.2
 	go.opentelemetry.io/otel v1.35.0
 	go.opentelemetry.io/otel/exporters/prometheus v0.57.0
 	go.opentelemetry.io/otel/exporters/stdout/stdoutmetric v1.34.0
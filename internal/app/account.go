@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// ExportUserData collects all data of a user. It reads from a single snapshot so the export is consistent.
+func (s *TaskManagerService) ExportUserData(ctx context.Context, userID uuid.UUID) (*models.UserExport, error) {
+	s.Log.Debug("Starting ExportUserData", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	export := &models.UserExport{FormatVersion: models.ExportFormatVersion, ExportedAt: time.Now().UTC()}
+	if export.User, err = s.db.GetUserByIDTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	if export.User == nil {
+		err = fmt.Errorf("user %s not found: %w", userID, sql.ErrNoRows)
+		return nil, err
+	}
+	if export.Settings, err = s.db.GetUserSettingsTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	if export.Workspaces, err = s.db.ListWorkspacesTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	if export.Projects, err = s.db.ListProjectsTx(ctx, tx, userID, nil, true); err != nil {
+		return nil, err
+	}
+	if export.Labels, err = s.db.ListLabelsTx(ctx, tx, userID, nil); err != nil {
+		return nil, err
+	}
+	if export.Tasks, err = s.db.ListAllTasksTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	if err = s.loadTaskDetailsTx(ctx, tx, export.Tasks...); err != nil {
+		return nil, err
+	}
+	if export.Comments, err = s.db.ListUserCommentsTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	if err = s.db.LoadCommentMentionsTx(ctx, tx, export.Comments...); err != nil {
+		return nil, err
+	}
+	if export.Tokens, err = s.db.ListTokensTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("User data exported", slog.String("userID", userID.String()), slog.Int("tasks", len(export.Tasks)))
+	return export, nil
+}
+
+// DeleteUser deletes a user together with their personal data. Shared workspaces keep the
+// content the user created, workspaces left without members are deleted.
+func (s *TaskManagerService) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	s.Log.Debug("Starting DeleteUser", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.ReleaseUserWorkspacesTx(ctx, tx, userID); err != nil {
+		return err
+	}
+	if err = s.db.DeleteUserTx(ctx, tx, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user %s not found: %w", userID, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Info("User deleted", slog.String("userID", userID.String()))
+	return nil
+}
+
+// DeleteUserByKratosID deletes the user of a Kratos identity like DeleteUser.
+func (s *TaskManagerService) DeleteUserByKratosID(ctx context.Context, kratosID string) error {
+	s.Log.Debug("Starting DeleteUserByKratosID", slog.String("kratosID", kratosID))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	user, err := s.db.GetUserByKratosIDTx(ctx, tx, kratosID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		err = fmt.Errorf("user with Kratos ID %s not found: %w", kratosID, sql.ErrNoRows)
+		return err
+	}
+	if err = s.db.ReleaseUserWorkspacesTx(ctx, tx, user.ID); err != nil {
+		return err
+	}
+	userID, err := s.db.DeleteUserByKratosIDTx(ctx, tx, kratosID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user with Kratos ID %s not found: %w", kratosID, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Info("User deleted", slog.String("userID", userID.String()), slog.String("kratosID", kratosID))
+	return nil
+}
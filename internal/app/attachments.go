@@ -0,0 +1,709 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/HellUpa/taskmanager/internal/storage"
+	"github.com/google/uuid"
+)
+
+// blobDeletionBatch is the number of queued blobs deleted per transaction.
+const blobDeletionBatch = 100
+
+// multipartStagingPrefix prefixes the staging files of single request uploads.
+const multipartStagingPrefix = "multipart-"
+
+// UploadAttachment stores a file sent in a single request as an attachment of a task.
+// The file counts against the storage quota of the user, who must be allowed to edit the task.
+func (s *TaskManagerService) UploadAttachment(ctx context.Context, taskID int32, userID uuid.UUID, filename string, r io.Reader) (*models.Attachment, error) {
+	s.Log.Debug("Starting UploadAttachment", slog.Int("taskID", int(taskID)), slog.String("userID", userID.String()))
+	filename, err := models.NormalizeFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stage the file first: its size and type are only known once it has been read.
+	if err := os.MkdirAll(s.attachments.StagingPath, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	f, err := os.CreateTemp(s.attachments.StagingPath, multipartStagingPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	size, err := io.Copy(f, io.LimitReader(r, s.attachments.MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive file: %w", err)
+	}
+	if size > s.attachments.MaxFileSize {
+		return nil, fmt.Errorf("%w: files may be at most %d bytes", models.ErrTooLarge, s.attachments.MaxFileSize)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("%w: file is empty", models.ErrInvalidInput)
+	}
+	contentType, err := s.sniffContentType(f)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessEdit); err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		TaskID:      taskID,
+		UserID:      &userID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+	}
+	if err = s.storeAttachmentTx(ctx, tx, attachment, f); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		s.deleteBlob(attachment.BlobKey)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Info("Attachment uploaded", slog.Int("taskID", int(taskID)), slog.Int("attachmentID", int(attachment.ID)), slog.Int64("size", size))
+	return attachment, nil
+}
+
+// CreateUpload starts a resumable upload of a file of the given size to a task.
+// The size is reserved from the storage quota of the user until the upload completes or expires.
+func (s *TaskManagerService) CreateUpload(ctx context.Context, taskID int32, userID uuid.UUID, filename string, size int64) (*models.AttachmentUpload, error) {
+	s.Log.Debug("Starting CreateUpload", slog.Int("taskID", int(taskID)), slog.String("userID", userID.String()))
+	filename, err := models.NormalizeFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: size must be positive", models.ErrInvalidInput)
+	}
+	if size > s.attachments.MaxFileSize {
+		return nil, fmt.Errorf("%w: files may be at most %d bytes", models.ErrTooLarge, s.attachments.MaxFileSize)
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessEdit); err != nil {
+		return nil, err
+	}
+	if err = s.reserveStorageTx(ctx, tx, userID, size); err != nil {
+		return nil, err
+	}
+
+	upload := &models.AttachmentUpload{
+		ID:        uuid.New(),
+		TaskID:    taskID,
+		UserID:    userID,
+		Filename:  filename,
+		Size:      size,
+		ExpiresAt: time.Now().Add(s.attachments.UploadTTL),
+	}
+	if err = s.db.CreateAttachmentUploadTx(ctx, tx, upload); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return upload, nil
+}
+
+// GetUpload retrieves a resumable upload the user started on a task.
+func (s *TaskManagerService) GetUpload(ctx context.Context, taskID int32, uploadID, userID uuid.UUID) (*models.AttachmentUpload, error) {
+	s.Log.Debug("Starting GetUpload", slog.String("uploadID", uploadID.String()), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	upload, err := s.db.GetAttachmentUploadTx(ctx, tx, taskID, uploadID, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil {
+		err = fmt.Errorf("upload with id %s not found: %w", uploadID, sql.ErrNoRows)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return upload, nil
+}
+
+// AppendUploadChunk appends a chunk read from r to a resumable upload. The chunk must start at the
+// current offset of the upload, otherwise models.ErrPreconditionFailed is returned. Once all bytes
+// have arrived the file becomes an attachment, which is returned along with the upload.
+func (s *TaskManagerService) AppendUploadChunk(ctx context.Context, taskID int32, uploadID, userID uuid.UUID, offset int64, r io.Reader) (*models.AttachmentUpload, *models.Attachment, error) {
+	s.Log.Debug("Starting AppendUploadChunk", slog.String("uploadID", uploadID.String()), slog.Int64("offset", offset))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessEdit); err != nil {
+		return nil, nil, err
+	}
+	// The row lock serializes chunks of the same upload.
+	upload, err := s.db.GetAttachmentUploadTx(ctx, tx, taskID, uploadID, userID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if upload == nil {
+		err = fmt.Errorf("upload with id %s not found: %w", uploadID, sql.ErrNoRows)
+		return nil, nil, err
+	}
+	if offset != upload.Offset {
+		err = fmt.Errorf("%w: upload continues at offset %d", models.ErrPreconditionFailed, upload.Offset)
+		return nil, nil, err
+	}
+
+	if err = os.MkdirAll(s.attachments.StagingPath, 0o750); err != nil {
+		err = fmt.Errorf("failed to create staging directory: %w", err)
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(s.stagingPath(uploadID), os.O_CREATE|os.O_RDWR, 0o640)
+	if err != nil {
+		err = fmt.Errorf("failed to open staging file: %w", err)
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	// Drop whatever a failed earlier chunk left behind the recorded offset.
+	if err = f.Truncate(upload.Offset); err != nil {
+		err = fmt.Errorf("failed to truncate staging file: %w", err)
+		return nil, nil, err
+	}
+	if _, err = f.Seek(upload.Offset, io.SeekStart); err != nil {
+		err = fmt.Errorf("failed to seek staging file: %w", err)
+		return nil, nil, err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, upload.Size-upload.Offset+1))
+	if err != nil {
+		err = fmt.Errorf("failed to receive chunk: %w", err)
+		return nil, nil, err
+	}
+	if upload.Offset+n > upload.Size {
+		err = fmt.Errorf("%w: upload was declared with %d bytes", models.ErrTooLarge, upload.Size)
+		return nil, nil, err
+	}
+	upload.Offset += n
+
+	if upload.Offset < upload.Size {
+		if err = s.db.SetUploadOffsetTx(ctx, tx, uploadID, upload.Offset); err != nil {
+			return nil, nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return upload, nil, nil
+	}
+
+	// The upload is complete. Dropping it first releases its reservation before the quota is checked again.
+	if err = s.db.DeleteAttachmentUploadTx(ctx, tx, uploadID); err != nil {
+		return nil, nil, err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		err = fmt.Errorf("failed to seek staging file: %w", err)
+		return nil, nil, err
+	}
+	contentType, sniffErr := s.sniffContentType(f)
+	if sniffErr != nil {
+		// A file of an unsupported type can never complete, so the upload is dropped.
+		if err = tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		s.removeStagingFile(uploadID)
+		return nil, nil, sniffErr
+	}
+
+	attachment := &models.Attachment{
+		TaskID:      taskID,
+		UserID:      &userID,
+		Filename:    upload.Filename,
+		ContentType: contentType,
+		Size:        upload.Size,
+	}
+	if err = s.storeAttachmentTx(ctx, tx, attachment, f); err != nil {
+		return nil, nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		s.deleteBlob(attachment.BlobKey)
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.removeStagingFile(uploadID)
+	s.Log.Info("Attachment uploaded", slog.Int("taskID", int(taskID)), slog.Int("attachmentID", int(attachment.ID)), slog.Int64("size", attachment.Size))
+	return upload, attachment, nil
+}
+
+// AbortUpload cancels a resumable upload and releases its quota reservation.
+func (s *TaskManagerService) AbortUpload(ctx context.Context, taskID int32, uploadID, userID uuid.UUID) error {
+	s.Log.Debug("Starting AbortUpload", slog.String("uploadID", uploadID.String()), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	upload, err := s.db.GetAttachmentUploadTx(ctx, tx, taskID, uploadID, userID, true)
+	if err != nil {
+		return err
+	}
+	if upload == nil {
+		err = fmt.Errorf("upload with id %s not found: %w", uploadID, sql.ErrNoRows)
+		return err
+	}
+	if err = s.db.DeleteAttachmentUploadTx(ctx, tx, uploadID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.removeStagingFile(uploadID)
+	return nil
+}
+
+// ListAttachments retrieves the attachments of a task visible to the user.
+func (s *TaskManagerService) ListAttachments(ctx context.Context, taskID int32, userID uuid.UUID) ([]*models.Attachment, error) {
+	s.Log.Debug("Starting ListAttachments", slog.Int("taskID", int(taskID)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessView); err != nil {
+		return nil, err
+	}
+	attachments, err := s.db.ListAttachmentsTx(ctx, tx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return attachments, nil
+}
+
+// GetAttachment retrieves an attachment of a task visible to the user.
+func (s *TaskManagerService) GetAttachment(ctx context.Context, taskID, id int32, userID uuid.UUID) (*models.Attachment, error) {
+	s.Log.Debug("Starting GetAttachment", slog.Int("attachmentID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessView); err != nil {
+		return nil, err
+	}
+	attachment, err := s.db.GetAttachmentTx(ctx, tx, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment == nil {
+		err = fmt.Errorf("attachment with id %d not found: %w", id, sql.ErrNoRows)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return attachment, nil
+}
+
+// OpenAttachment retrieves an attachment visible to the user along with a reader over its contents.
+// The caller must close the reader.
+func (s *TaskManagerService) OpenAttachment(ctx context.Context, taskID, id int32, userID uuid.UUID) (*models.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := s.GetAttachment(ctx, taskID, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.blobs.Open(ctx, attachment.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, fmt.Errorf("contents of attachment %d not found: %w", id, sql.ErrNoRows)
+		}
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment deletes an attachment. The uploader may delete it while they can edit the task,
+// the task owner at any time. Its blob is removed right away when possible, otherwise by the cleaner.
+func (s *TaskManagerService) DeleteAttachment(ctx context.Context, taskID, id int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting DeleteAttachment", slog.Int("attachmentID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	access, err := s.db.GetTaskAccessTx(ctx, tx, taskID, userID)
+	if err != nil {
+		return err
+	}
+	if access == "" {
+		err = fmt.Errorf("task with id %d not found: %w", taskID, sql.ErrNoRows)
+		return err
+	}
+	attachment, err := s.db.GetAttachmentTx(ctx, tx, taskID, id)
+	if err != nil {
+		return err
+	}
+	if attachment == nil {
+		err = fmt.Errorf("attachment with id %d not found: %w", id, sql.ErrNoRows)
+		return err
+	}
+	uploader := attachment.UserID != nil && *attachment.UserID == userID
+	if access != models.TaskAccessOwner && !(uploader && models.TaskAccessAllows(access, models.TaskAccessEdit)) {
+		err = fmt.Errorf("only the uploader or the task owner may delete attachment %d: %w", id, models.ErrForbidden)
+		return err
+	}
+	if err = s.db.DeleteAttachmentTx(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if err := s.purgeDeletedBlob(ctx, attachment.BlobKey); err != nil {
+		s.Log.Warn("Failed to delete attachment blob, leaving it to the cleaner", slog.String("key", attachment.BlobKey), logu.Err(err))
+	}
+	return nil
+}
+
+// PurgeDeletedBlobs deletes the blobs of deleted attachments from the blob store and returns their number.
+// Deleting an attachment, directly or with its task, workspace or owner, queues its blob in the database.
+func (s *TaskManagerService) PurgeDeletedBlobs(ctx context.Context) (int, error) {
+	var purged int
+	for {
+		n, err := s.purgeDeletedBlobBatch(ctx)
+		purged += n
+		if err != nil || n < blobDeletionBatch {
+			return purged, err
+		}
+	}
+}
+
+// purgeDeletedBlob deletes the blob of one deleted attachment and takes it off the deletion queue.
+// Other queued blobs are left to the cleaner.
+func (s *TaskManagerService) purgeDeletedBlob(ctx context.Context, key string) error {
+	if err := s.blobs.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.DeleteBlobDeletionTx(ctx, tx, key); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// purgeDeletedBlobBatch deletes one batch of queued blobs.
+func (s *TaskManagerService) purgeDeletedBlobBatch(ctx context.Context) (int, error) {
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	keys, err := s.db.ListBlobDeletionsTx(ctx, tx, blobDeletionBatch)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		if err = s.blobs.Delete(ctx, key); err != nil {
+			return 0, err
+		}
+		if err = s.db.DeleteBlobDeletionTx(ctx, tx, key); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(keys), nil
+}
+
+// PurgeExpiredUploads deletes expired resumable uploads and staging files no upload refers to
+// any more, and returns the number of uploads deleted.
+func (s *TaskManagerService) PurgeExpiredUploads(ctx context.Context) (int64, error) {
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	purged, err := s.db.DeleteExpiredUploadsTx(ctx, tx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(s.attachments.StagingPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		err = fmt.Errorf("failed to read staging directory: %w", err)
+		return 0, err
+	}
+	err = nil
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if strings.HasPrefix(name, multipartStagingPrefix) {
+			// Left behind by a crash while receiving a single request upload.
+			if info, infoErr := entry.Info(); infoErr == nil && time.Since(info.ModTime()) > s.attachments.UploadTTL {
+				os.Remove(filepath.Join(s.attachments.StagingPath, name))
+			}
+			continue
+		}
+		uploadID, parseErr := uuid.Parse(name)
+		if parseErr != nil {
+			continue
+		}
+		var exists bool
+		if exists, err = s.db.AttachmentUploadExistsTx(ctx, tx, uploadID); err != nil {
+			return 0, err
+		}
+		if !exists {
+			s.removeStagingFile(uploadID)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return purged, nil
+}
+
+// RunAttachmentCleaner purges expired uploads and the blobs of deleted attachments every configured
+// interval until ctx is done.
+func (s *TaskManagerService) RunAttachmentCleaner(ctx context.Context) {
+	interval := s.attachments.CleanupInterval
+	if interval <= 0 {
+		s.Log.Info("Attachment cleaner disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			uploads, err := s.PurgeExpiredUploads(ctx)
+			if err != nil {
+				s.Log.Error("Failed to purge expired uploads", logu.Err(err))
+			} else if uploads > 0 {
+				s.Log.Info("Expired uploads purged", slog.Int64("count", uploads))
+			}
+			blobs, err := s.PurgeDeletedBlobs(ctx)
+			if err != nil {
+				s.Log.Error("Failed to purge deleted attachment blobs", logu.Err(err))
+			}
+			if blobs > 0 {
+				s.Log.Info("Deleted attachment blobs purged", slog.Int("count", blobs))
+			}
+		}
+	}
+}
+
+// reserveStorageTx checks that size more bytes fit in the storage quota of the user.
+// The check holds a lock on the user's storage until the transaction ends, so that concurrent
+// uploads cannot both pass it. A quota of zero or less means unlimited storage.
+func (s *TaskManagerService) reserveStorageTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, size int64) error {
+	if s.attachments.UserQuota <= 0 {
+		return nil
+	}
+	if err := s.db.LockUserStorageTx(ctx, tx, userID); err != nil {
+		return err
+	}
+	used, err := s.db.UserStorageUsageTx(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if used+size > s.attachments.UserQuota {
+		return fmt.Errorf("%w: %d of %d bytes used", models.ErrQuotaExceeded, used, s.attachments.UserQuota)
+	}
+	return nil
+}
+
+// storeAttachmentTx checks the quota of the uploader, writes the contents to the blob store
+// and records the attachment within a transaction. The blob is deleted again on failure;
+// when the transaction later fails to commit, the caller must delete it.
+func (s *TaskManagerService) storeAttachmentTx(ctx context.Context, tx *sql.Tx, attachment *models.Attachment, content io.Reader) error {
+	if err := s.reserveStorageTx(ctx, tx, *attachment.UserID, attachment.Size); err != nil {
+		return err
+	}
+
+	id := uuid.NewString()
+	attachment.BlobKey = id[:2] + "/" + id
+	if err := s.blobs.Put(ctx, attachment.BlobKey, content, attachment.Size, attachment.ContentType); err != nil {
+		return err
+	}
+	if err := s.db.CreateAttachmentTx(ctx, tx, attachment); err != nil {
+		s.deleteBlob(attachment.BlobKey)
+		return err
+	}
+	return nil
+}
+
+// sniffContentType detects the content type of a file from its first bytes, checks that it is
+// allowed and rewinds the file.
+func (s *TaskManagerService) sniffContentType(f io.ReadSeeker) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to seek file: %w", err)
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !slices.Contains(s.attachments.AllowedTypes, mediaType) {
+		return "", fmt.Errorf("%w: %s", models.ErrUnsupportedType, contentType)
+	}
+	return contentType, nil
+}
+
+// stagingPath returns the file holding the chunks received for a resumable upload.
+func (s *TaskManagerService) stagingPath(uploadID uuid.UUID) string {
+	return filepath.Join(s.attachments.StagingPath, uploadID.String())
+}
+
+// removeStagingFile removes the staging file of a resumable upload, if any.
+func (s *TaskManagerService) removeStagingFile(uploadID uuid.UUID) {
+	if err := os.Remove(s.stagingPath(uploadID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.Log.Warn("Failed to remove staging file", slog.String("uploadID", uploadID.String()), logu.Err(err))
+	}
+}
+
+// deleteBlob deletes a blob that was stored for an attachment that could not be recorded.
+// It detaches from the request context, which may be what caused the failure.
+func (s *TaskManagerService) deleteBlob(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.blobs.Delete(ctx, key); err != nil {
+		s.Log.Warn("Failed to delete orphaned blob", slog.String("key", key), logu.Err(err))
+	}
+}
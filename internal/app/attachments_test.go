@@ -0,0 +1,44 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/HellUpa/taskmanager/internal/storage"
+)
+
+func TestPurgeDeletedBlob(t *testing.T) {
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"ab/abc", "cd/cde"} {
+		if err := blobs.Put(t.Context(), key, strings.NewReader("data"), 4, "text/plain"); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+	}
+	s, fake := newTestService(t, fakeQuery{match: "DELETE FROM blob_deletions WHERE blob_key = $1", rowsAffected: 1})
+	s.blobs = blobs
+
+	if err := s.purgeDeletedBlob(t.Context(), "ab/abc"); err != nil {
+		t.Fatalf("purgeDeletedBlob: %v", err)
+	}
+
+	if _, err := blobs.Open(t.Context(), "ab/abc"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("open deleted blob: err = %v, want ErrNotFound", err)
+	}
+	other, err := blobs.Open(t.Context(), "cd/cde")
+	if err != nil {
+		t.Fatalf("blob of another attachment was deleted: %v", err)
+	}
+	other.Close()
+
+	dequeued := fake.called("DELETE FROM blob_deletions")
+	if len(dequeued) != 1 || dequeued[0].args[0] != "ab/abc" {
+		t.Errorf("dequeued %v, want only the deleted blob", dequeued)
+	}
+	if len(fake.called("SELECT blob_key FROM blob_deletions")) != 0 {
+		t.Error("went through the deletion queue, which is left to the cleaner")
+	}
+}
@@ -0,0 +1,344 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// mentionPattern matches @mentions of an email address or a display name without spaces.
+// The mention must not follow a word character, so email addresses in the text are not mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.+-]+@[\w-]+(?:\.[\w-]+)+|[\w.-]+)`)
+
+// CreateComment adds a comment by the user to a task. Anyone who can view the task may comment.
+func (s *TaskManagerService) CreateComment(ctx context.Context, comment *models.TaskComment, userID uuid.UUID) error {
+	s.Log.Debug("Starting CreateComment", slog.Int("taskID", int(comment.TaskID)), slog.String("userID", userID.String()))
+	if err := comment.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, comment.TaskID, userID, models.TaskAccessView); err != nil {
+		return err
+	}
+	comment.UserID = &userID
+	if err = s.db.CreateTaskCommentTx(ctx, tx, comment); err != nil {
+		return err
+	}
+	if err = s.saveMentionsTx(ctx, tx, comment); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Comment created successfully", slog.Int("commentID", int(comment.ID)))
+	return nil
+}
+
+// ListComments returns the comments of a task, oldest first.
+func (s *TaskManagerService) ListComments(ctx context.Context, taskID int32, userID uuid.UUID) ([]*models.TaskComment, error) {
+	s.Log.Debug("Starting ListComments", slog.Int("taskID", int(taskID)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessView); err != nil {
+		return nil, err
+	}
+	comments, err := s.db.ListTaskCommentsTx(ctx, tx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.db.LoadCommentMentionsTx(ctx, tx, comments...); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return comments, nil
+}
+
+// UpdateComment replaces the body of a comment, keeping the previous one in its history.
+// Only the author may edit a comment, and only while they can still view the task.
+func (s *TaskManagerService) UpdateComment(ctx context.Context, comment *models.TaskComment, userID uuid.UUID) error {
+	s.Log.Debug("Starting UpdateComment", slog.Int("commentID", int(comment.ID)), slog.String("userID", userID.String()))
+	if err := comment.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	prev, err := s.taskCommentTx(ctx, tx, comment.TaskID, comment.ID, userID)
+	if err != nil {
+		return err
+	}
+	if prev.UserID == nil || *prev.UserID != userID {
+		err = fmt.Errorf("comment %d was written by another user: %w", comment.ID, models.ErrForbidden)
+		return err
+	}
+
+	body := comment.Body
+	*comment = *prev
+	if body != prev.Body {
+		comment.Body = body
+		if err = s.db.UpdateTaskCommentTx(ctx, tx, comment); err != nil {
+			return err
+		}
+		if err = s.saveMentionsTx(ctx, tx, comment); err != nil {
+			return err
+		}
+	} else if err = s.db.LoadCommentMentionsTx(ctx, tx, comment); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Comment updated successfully", slog.Int("commentID", int(comment.ID)))
+	return nil
+}
+
+// DeleteComment deletes a comment with its history. The author and the owners of the task may delete it.
+func (s *TaskManagerService) DeleteComment(ctx context.Context, taskID, id int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting DeleteComment", slog.Int("commentID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	comment, err := s.taskCommentTx(ctx, tx, taskID, id, userID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID == nil || *comment.UserID != userID {
+		if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessOwner); err != nil {
+			return err
+		}
+	}
+	if err = s.db.DeleteTaskCommentTx(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Comment deleted successfully", slog.Int("commentID", int(id)))
+	return nil
+}
+
+// ListCommentHistory returns the previous bodies of a comment, oldest first.
+func (s *TaskManagerService) ListCommentHistory(ctx context.Context, taskID, id int32, userID uuid.UUID) ([]*models.TaskCommentRevision, error) {
+	s.Log.Debug("Starting ListCommentHistory", slog.Int("commentID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if _, err = s.taskCommentTx(ctx, tx, taskID, id, userID); err != nil {
+		return nil, err
+	}
+	revisions, err := s.db.ListCommentRevisionsTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetTaskActivity returns the activity feed of a task: its comments and recorded events, oldest first.
+func (s *TaskManagerService) GetTaskActivity(ctx context.Context, taskID int32, userID uuid.UUID) ([]*models.TaskActivityItem, error) {
+	s.Log.Debug("Starting GetTaskActivity", slog.Int("taskID", int(taskID)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessView); err != nil {
+		return nil, err
+	}
+	comments, err := s.db.ListTaskCommentsTx(ctx, tx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.db.LoadCommentMentionsTx(ctx, tx, comments...); err != nil {
+		return nil, err
+	}
+	events, err := s.db.ListTaskActivityTx(ctx, tx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	items := make([]*models.TaskActivityItem, 0, len(comments)+len(events))
+	for _, c := range comments {
+		items = append(items, &models.TaskActivityItem{Type: models.ActivityItemComment, CreatedAt: c.CreatedAt, Comment: c})
+	}
+	for _, e := range events {
+		items = append(items, &models.TaskActivityItem{Type: models.ActivityItemEvent, CreatedAt: e.CreatedAt, Event: e})
+	}
+	slices.SortStableFunc(items, func(a, b *models.TaskActivityItem) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return items, nil
+}
+
+// taskCommentTx returns a comment of a task the user can view, failing with sql.ErrNoRows otherwise.
+func (s *TaskManagerService) taskCommentTx(ctx context.Context, tx *sql.Tx, taskID, id int32, userID uuid.UUID) (*models.TaskComment, error) {
+	if err := s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessView); err != nil {
+		return nil, err
+	}
+	comment, err := s.db.GetTaskCommentTx(ctx, tx, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil {
+		return nil, fmt.Errorf("comment with id %d not found: %w", id, sql.ErrNoRows)
+	}
+	return comment, nil
+}
+
+// saveMentionsTx resolves the @mentions in the comment body to users who can view the task
+// and stores them. Mentions of anyone else are left as plain text.
+func (s *TaskManagerService) saveMentionsTx(ctx context.Context, tx *sql.Tx, comment *models.TaskComment) error {
+	comment.Mentions = []uuid.UUID{}
+	handles := parseMentions(comment.Body)
+	if len(handles) > 0 {
+		audience, err := s.db.ListTaskAudienceTx(ctx, tx, comment.TaskID)
+		if err != nil {
+			return err
+		}
+		for _, user := range audience {
+			if handles[strings.ToLower(user.Email)] || handles[strings.ToLower(user.DisplayName)] {
+				comment.Mentions = append(comment.Mentions, user.ID)
+			}
+		}
+		slices.SortFunc(comment.Mentions, func(a, b uuid.UUID) int {
+			return cmp.Compare(a.String(), b.String())
+		})
+	}
+	return s.db.SetCommentMentionsTx(ctx, tx, comment.ID, comment.Mentions)
+}
+
+// parseMentions returns the lowercased handles @mentioned in a comment body.
+func parseMentions(body string) map[string]bool {
+	handles := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		if handle := strings.TrimRight(m[1], ".-"); handle != "" {
+			handles[strings.ToLower(handle)] = true
+		}
+	}
+	return handles
+}
+
+// recordTaskActivityTx records the changes of a task shown in its activity feed:
+// status changes and moved due dates. task.UserID is the user making the change.
+func (s *TaskManagerService) recordTaskActivityTx(ctx context.Context, tx *sql.Tx, prev, task *models.Task) error {
+	var events []*models.TaskActivityEvent
+	if task.Status != prev.Status {
+		events = append(events, &models.TaskActivityEvent{
+			Kind:     models.TaskActivityStatusChanged,
+			OldValue: &prev.Status,
+			NewValue: &task.Status,
+		})
+	}
+	if !sameTime(prev.DueDate, task.DueDate) {
+		events = append(events, &models.TaskActivityEvent{
+			Kind:     models.TaskActivityDueDateChanged,
+			OldValue: formatTime(prev.DueDate),
+			NewValue: formatTime(task.DueDate),
+		})
+	}
+
+	for _, event := range events {
+		event.TaskID, event.UserID = task.ID, &task.UserID
+		if err := s.db.CreateTaskActivityTx(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameTime reports whether two optional times are both nil or the same instant.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// formatTime formats an optional time as RFC 3339 in UTC.
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC().Format(time.RFC3339)
+	return &v
+}
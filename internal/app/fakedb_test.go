@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/HellUpa/taskmanager/internal/config"
+	"github.com/HellUpa/taskmanager/internal/db"
+)
+
+// fakeDBCount numbers the fake databases, each test gets its own driver registration.
+var fakeDBCount atomic.Int64
+
+// fakeQuery answers every statement containing match with rows of the given columns,
+// or with rowsAffected for statements run through Exec.
+type fakeQuery struct {
+	match        string
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected int64
+}
+
+// fakeCall is a statement the service ran against the fake database.
+type fakeCall struct {
+	query string
+	args  []driver.Value
+}
+
+// fakeDB is a database/sql driver answering the scripted queries and recording all statements.
+// Any statement without a matching query fails the test.
+type fakeDB struct {
+	t       *testing.T
+	queries []fakeQuery
+	calls   []fakeCall
+}
+
+// newTestService returns a service backed by a fake database answering the given queries.
+func newTestService(t *testing.T, queries ...fakeQuery) (*TaskManagerService, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{t: t, queries: queries}
+	name := fmt.Sprintf("app-fakedb-%d", fakeDBCount.Add(1))
+	sql.Register(name, fake)
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("open fake database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewTaskManagerService(log, &db.PostgresDB{DB: sqlDB}, config.TasksConfig{}, nil, config.AttachmentsConfig{}), fake
+}
+
+// beginTx starts a transaction on the fake database of s.
+func beginTx(t *testing.T, s *TaskManagerService) *sql.Tx {
+	t.Helper()
+	tx, err := s.db.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("begin transaction: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+// called returns the recorded statements containing match.
+func (d *fakeDB) called(match string) []fakeCall {
+	var calls []fakeCall
+	for _, c := range d.calls {
+		if strings.Contains(c.query, match) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func (d *fakeDB) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+func (d *fakeDB) answer(query string, args []driver.Value) (*fakeQuery, error) {
+	d.calls = append(d.calls, fakeCall{query: query, args: args})
+	for i := range d.queries {
+		if strings.Contains(query, d.queries[i].match) {
+			return &d.queries[i], nil
+		}
+	}
+	d.t.Errorf("unexpected statement: %s", query)
+	return nil, fmt.Errorf("unexpected statement")
+}
+
+type fakeConn struct{ d *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	d     *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	q, err := s.d.answer(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(q.rowsAffected), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	q, err := s.d.answer(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: q.columns, values: q.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
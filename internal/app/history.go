@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// restorableFields are the task fields a restore brings back. Workspace, assignee and series
+// are left alone, as is the recurrence rule, which is not part of the history. Label changes are
+// recorded as label_ids, but not restored: the labels may have been deleted or renamed since.
+var restorableFields = []string{"title", "description", "due_date", "due_all_day", "status", "priority", "project_id", "parent_id"}
+
+// beginAuditedTx begins a transaction whose changes to tasks are recorded in their history
+// as made by userID in the request of ctx.
+func (s *TaskManagerService) beginAuditedTx(ctx context.Context, userID uuid.UUID) (*sql.Tx, error) {
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := s.db.SetAuditContextTx(ctx, tx, userID, middleware.GetReqID(ctx)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// GetTaskHistory returns the recorded creates and updates of a task visible to the user, oldest first.
+func (s *TaskManagerService) GetTaskHistory(ctx context.Context, id int32, userID uuid.UUID) ([]*models.TaskEvent, error) {
+	s.Log.Debug("Starting GetTaskHistory", slog.Int("taskID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, id, userID, models.TaskAccessView); err != nil {
+		return nil, err
+	}
+	events, err := s.db.ListTaskEventsTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return events, nil
+}
+
+// RestoreTaskRevision brings the fields of a task back to their values at an earlier version,
+// as a merge patch of everything changed since. The restore is a change like any other:
+// it needs edit access, passes the usual checks and adds a new version to the history.
+// A non-nil ifMatch only restores while the task has one of the given versions.
+func (s *TaskManagerService) RestoreTaskRevision(ctx context.Context, id, version int32, userID uuid.UUID, ifMatch []int32) (*models.Task, error) {
+	s.Log.Debug("Starting RestoreTaskRevision", slog.Int("taskID", int(id)), slog.Int("version", int(version)))
+	task, err := s.GetTask(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task with id %d not found: %w", id, sql.ErrNoRows)
+	}
+	if ifMatch != nil && !slices.Contains(ifMatch, task.Version) {
+		return nil, models.ErrPreconditionFailed
+	}
+	events, err := s.GetTaskHistory(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := revisionPatch(task.Version, events, version)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return task, nil
+	}
+	patch, err := json.Marshal(members)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode restore patch: %w", err)
+	}
+	// The history was read outside the patch's transaction, so the patch only applies
+	// to the version it was computed from.
+	return s.PatchTask(ctx, id, userID, patch, models.TaskUpdateOptions{IfMatch: []int32{task.Version}})
+}
+
+// revisionPatch builds the merge patch taking a task at version current back to version target
+// from its history: each field changed since gets the old value of its first change after target.
+func revisionPatch(current int32, events []*models.TaskEvent, target int32) (map[string]json.RawMessage, error) {
+	if target < 1 || target >= current {
+		return nil, fmt.Errorf("%w: revision must be between 1 and %d", models.ErrInvalidInput, current-1)
+	}
+	// Changes made before the history was recorded cannot be undone.
+	if !slices.ContainsFunc(events, func(e *models.TaskEvent) bool { return e.Version <= target }) {
+		return nil, fmt.Errorf("%w: revision %d predates the recorded history", models.ErrInvalidInput, target)
+	}
+
+	members := map[string]json.RawMessage{}
+	for i := len(events) - 1; i >= 0 && events[i].Version > target; i-- {
+		for name, change := range events[i].Changes {
+			if slices.Contains(restorableFields, name) {
+				members[name] = change.Old
+			}
+		}
+	}
+	return members, nil
+}
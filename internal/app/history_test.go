@@ -0,0 +1,90 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+)
+
+// change builds a field change from JSON values.
+func change(old, new string) models.FieldChange {
+	return models.FieldChange{Old: json.RawMessage(old), New: json.RawMessage(new)}
+}
+
+func TestRevisionPatch(t *testing.T) {
+	events := []*models.TaskEvent{
+		{Version: 1, Action: models.TaskEventCreated, Changes: map[string]models.FieldChange{
+			"title": change(`null`, `"Draft"`), "status": change(`null`, `"todo"`)}},
+		{Version: 2, Action: models.TaskEventUpdated, Changes: map[string]models.FieldChange{
+			"title": change(`"Draft"`, `"Report"`)}},
+		{Version: 3, Action: models.TaskEventUpdated, Changes: map[string]models.FieldChange{
+			"label_ids": change(`[]`, `[4]`)}},
+		{Version: 4, Action: models.TaskEventUpdated, Changes: map[string]models.FieldChange{
+			"title": change(`"Report"`, `"Final report"`), "status": change(`"todo"`, `"done"`), "completed_at": change(`null`, `"2026-01-01T00:00:00Z"`)}},
+	}
+
+	tests := []struct {
+		name   string
+		target int32
+		want   map[string]string
+	}{
+		{name: "first version", target: 1, want: map[string]string{"title": `"Draft"`, "status": `"todo"`}},
+		{name: "oldest value since the target wins", target: 2, want: map[string]string{"title": `"Report"`, "status": `"todo"`}},
+		{name: "previous version", target: 3, want: map[string]string{"title": `"Report"`, "status": `"todo"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := revisionPatch(4, events, tt.target)
+			if err != nil {
+				t.Fatalf("revisionPatch: %v", err)
+			}
+			if len(members) != len(tt.want) {
+				t.Errorf("patch = %s, want %v", members, tt.want)
+			}
+			for name, want := range tt.want {
+				if got := string(members[name]); got != want {
+					t.Errorf("%s = %s, want %s", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRevisionPatchSkipsLabels(t *testing.T) {
+	events := []*models.TaskEvent{
+		{Version: 1, Action: models.TaskEventCreated, Changes: map[string]models.FieldChange{"title": change(`null`, `"Draft"`)}},
+		{Version: 2, Action: models.TaskEventUpdated, Changes: map[string]models.FieldChange{"label_ids": change(`[1]`, `[1,2]`)}},
+	}
+	members, err := revisionPatch(2, events, 1)
+	if err != nil {
+		t.Fatalf("revisionPatch: %v", err)
+	}
+	if len(members) != 0 {
+		t.Errorf("patch = %s, want labels left alone", members)
+	}
+}
+
+func TestRevisionPatchRejects(t *testing.T) {
+	events := []*models.TaskEvent{
+		{Version: 3, Action: models.TaskEventUpdated, Changes: map[string]models.FieldChange{"title": change(`"a"`, `"b"`)}},
+		{Version: 4, Action: models.TaskEventUpdated, Changes: map[string]models.FieldChange{"title": change(`"b"`, `"c"`)}},
+	}
+	tests := []struct {
+		name   string
+		target int32
+	}{
+		{name: "current version", target: 4},
+		{name: "future version", target: 5},
+		{name: "zero", target: 0},
+		{name: "before the recorded history", target: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := revisionPatch(4, events, tt.target); !errors.Is(err, models.ErrInvalidInput) {
+				t.Errorf("err = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// BeginIdempotentRequest claims an idempotency key for a request identified by requestHash,
+// leasing it for the configured IdempotencyLease. It returns nil when the caller should process the request and store its response with
+// CompleteIdempotentRequest, or the stored record when the response should be replayed.
+// A key still held by a request in progress yields models.ErrIdempotencyKeyInUse,
+// a key used for a different request models.ErrIdempotencyKeyMismatch.
+func (s *TaskManagerService) BeginIdempotentRequest(ctx context.Context, userID uuid.UUID, key, requestHash string) (*models.IdempotencyRecord, error) {
+	s.Log.Debug("Starting BeginIdempotentRequest", slog.String("userID", userID.String()), slog.String("key", key))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	rec := &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(s.cfg.IdempotencyLease),
+	}
+	claimed, err := s.db.ClaimIdempotencyKeyTx(ctx, tx, rec)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored *models.IdempotencyRecord
+	if !claimed {
+		if stored, err = s.db.GetIdempotencyKeyTx(ctx, tx, userID, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	switch {
+	case claimed:
+		return nil, nil
+	case stored == nil:
+		// The key expired and was purged in between, the client may simply retry.
+		return nil, models.ErrIdempotencyKeyInUse
+	case stored.RequestHash != requestHash:
+		return nil, models.ErrIdempotencyKeyMismatch
+	case stored.StatusCode == nil:
+		return nil, models.ErrIdempotencyKeyInUse
+	}
+	s.Log.Debug("Replaying idempotent request", slog.String("key", key), slog.Int("status", *stored.StatusCode))
+	return stored, nil
+}
+
+// CompleteIdempotentRequest stores the response of a request whose key was claimed by BeginIdempotentRequest
+// and keeps it for the configured IdempotencyTTL.
+func (s *TaskManagerService) CompleteIdempotentRequest(ctx context.Context, rec *models.IdempotencyRecord) error {
+	s.Log.Debug("Starting CompleteIdempotentRequest", slog.String("key", rec.Key))
+	rec.ExpiresAt = time.Now().Add(s.cfg.IdempotencyTTL)
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.CompleteIdempotencyKeyTx(ctx, tx, rec); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops a claimed key without storing a response, so the request can be retried.
+func (s *TaskManagerService) ReleaseIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) error {
+	s.Log.Debug("Starting ReleaseIdempotencyKey", slog.String("key", key))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.DeleteIdempotencyKeyTx(ctx, tx, userID, key); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys deletes all expired idempotency keys and returns their number.
+func (s *TaskManagerService) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	purged, err := s.db.DeleteExpiredIdempotencyKeysTx(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return purged, nil
+}
+
+// RunIdempotencySweeper purges expired idempotency keys every configured interval until ctx is done.
+func (s *TaskManagerService) RunIdempotencySweeper(ctx context.Context) {
+	interval := s.cfg.IdempotencySweepInterval
+	if interval <= 0 {
+		s.Log.Info("Idempotency key sweeper disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpiredIdempotencyKeys(ctx)
+			if err != nil {
+				s.Log.Error("Failed to purge expired idempotency keys", logu.Err(err))
+				continue
+			}
+			if purged > 0 {
+				s.Log.Info("Expired idempotency keys purged", slog.Int64("count", purged))
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// CreateLabel creates a new label in a workspace the user may edit, by default their personal one.
+func (s *TaskManagerService) CreateLabel(ctx context.Context, label *models.Label, userID uuid.UUID) error {
+	s.Log.Debug("Starting CreateLabel", slog.String("userID", userID.String()))
+	label.UserID = userID
+	if err := label.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if label.WorkspaceID, err = s.editableWorkspaceTx(ctx, tx, label.WorkspaceID, userID); err != nil {
+		return err
+	}
+	if err = s.db.CreateLabelTx(ctx, tx, label); err != nil {
+		return fmt.Errorf("failed to create label: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Label created successfully", slog.Int("labelID", int(label.ID)))
+	return nil
+}
+
+// GetLabel retrieves a label by its ID.
+func (s *TaskManagerService) GetLabel(ctx context.Context, id int32, userID uuid.UUID) (*models.Label, error) {
+	s.Log.Debug("Starting GetLabel", slog.Int("labelID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	label, err := s.db.GetLabelTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get label: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return label, nil
+}
+
+// ListLabels returns the labels of the user's workspaces.
+// A non-nil workspaceID restricts the list to that workspace.
+func (s *TaskManagerService) ListLabels(ctx context.Context, userID uuid.UUID, workspaceID *int32) ([]*models.Label, error) {
+	s.Log.Debug("Starting ListLabels", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	labels, err := s.db.ListLabelsTx(ctx, tx, userID, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labels: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Labels listed successfully", slog.Int("count", len(labels)))
+	return labels, nil
+}
+
+// UpdateLabel renames or recolors a label.
+func (s *TaskManagerService) UpdateLabel(ctx context.Context, label *models.Label) error {
+	s.Log.Debug("Starting UpdateLabel", slog.Int("labelID", int(label.ID)))
+	if err := label.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if _, err = s.editableLabelTx(ctx, tx, label.ID, label.UserID); err != nil {
+		return err
+	}
+	if err = s.db.UpdateLabelTx(ctx, tx, label); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("label with id %d not found: %w", label.ID, err)
+		}
+		return fmt.Errorf("failed to update label: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Label updated successfully", slog.Int("labelID", int(label.ID)))
+	return nil
+}
+
+// DeleteLabel deletes a label and detaches it from all tasks.
+func (s *TaskManagerService) DeleteLabel(ctx context.Context, id int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting DeleteLabel", slog.Int("labelID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if _, err = s.editableLabelTx(ctx, tx, id, userID); err != nil {
+		return err
+	}
+	if err = s.db.DeleteLabelTx(ctx, tx, id, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("label with id %d not found: %w", id, err)
+		}
+		return fmt.Errorf("failed to delete label: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Label deleted successfully", slog.Int("labelID", int(id)))
+	return nil
+}
+
+// AttachTaskLabel attaches a label to a task.
+func (s *TaskManagerService) AttachTaskLabel(ctx context.Context, taskID, labelID int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting AttachTaskLabel", slog.Int("taskID", int(taskID)), slog.Int("labelID", int(labelID)))
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.AttachTaskLabelTx(ctx, tx, taskID, labelID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("task %d or label %d not found: %w", taskID, labelID, err)
+		}
+		return fmt.Errorf("failed to attach label: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DetachTaskLabel removes a label from a task.
+func (s *TaskManagerService) DetachTaskLabel(ctx context.Context, taskID, labelID int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting DetachTaskLabel", slog.Int("taskID", int(taskID)), slog.Int("labelID", int(labelID)))
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.DetachTaskLabelTx(ctx, tx, taskID, labelID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("label %d is not attached to task %d: %w", labelID, taskID, err)
+		}
+		return fmt.Errorf("failed to detach label: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// editableLabelTx returns a label the user may change, failing with sql.ErrNoRows when it is
+// not visible to the user and with models.ErrForbidden when the user only views its workspace.
+func (s *TaskManagerService) editableLabelTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Label, error) {
+	label, err := s.db.GetLabelTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get label: %w", err)
+	}
+	if label == nil {
+		return nil, fmt.Errorf("label with id %d not found: %w", id, sql.ErrNoRows)
+	}
+	if _, err := s.requireWorkspaceRoleTx(ctx, tx, label.WorkspaceID, userID, models.CanEditWorkspaceContent); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
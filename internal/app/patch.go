@@ -0,0 +1,165 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// PatchTask applies an RFC 7396 JSON merge patch to a task and returns the task as stored.
+// Only the members present in the patch change. A null parent_id moves the task to the
+// top level, a null due_date removes the deadline, a null recurrence_rule stops its series
+// and a null label_ids detaches all labels.
+func (s *TaskManagerService) PatchTask(ctx context.Context, id int32, userID uuid.UUID, patch []byte, opts models.TaskUpdateOptions) (*models.Task, error) {
+	s.Log.Debug("Starting PatchTask", slog.Int("taskID", int(id)))
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &members); err != nil || members == nil {
+		return nil, fmt.Errorf("%w: merge patch must be a JSON object", models.ErrInvalidInput)
+	}
+
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	prev, err := s.db.GetTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if prev == nil {
+		err = fmt.Errorf("task with id %d not found: %w", id, sql.ErrNoRows)
+		return nil, err
+	}
+
+	task, err := mergeTaskPatch(prev, members)
+	if err != nil {
+		return nil, err
+	}
+	// The update is authorized for the patching user, who may not be the task's creator.
+	task.UserID = userID
+
+	if err = s.updateTaskTx(ctx, tx, prev, task, opts); err != nil {
+		return nil, err
+	}
+
+	if raw, ok := members["parent_id"]; ok && isJSONNull(raw) && prev.ParentID != nil {
+		// Only users editing the workspace may move the task; assignees and share holders get 403.
+		if err = s.db.SetTaskParentTx(ctx, tx, id, nil, userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = s.taskNotEditableTx(ctx, tx, id, userID)
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to set task parent: %w", err)
+		}
+	}
+
+	updated, err := s.reloadTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Task patched successfully", slog.Int("taskID", int(id)))
+	return updated, nil
+}
+
+// mergeTaskPatch builds the updated task from prev and the members of a merge patch.
+// Pointer fields absent from the patch stay nil so that UpdateTaskTx keeps their values.
+func mergeTaskPatch(prev *models.Task, members map[string]json.RawMessage) (*models.Task, error) {
+	task := &models.Task{
+		ID:          prev.ID,
+		UserID:      prev.UserID,
+		Title:       prev.Title,
+		Description: prev.Description,
+		DueDate:     prev.DueDate,
+		DueAllDay:   prev.DueAllDay,
+		Status:      prev.Status,
+		Priority:    prev.Priority,
+		Completed:   prev.Completed,
+	}
+
+	for name, raw := range members {
+		null := isJSONNull(raw)
+		var dst any
+		switch name {
+		case "title":
+			dst = &task.Title
+		case "description":
+			dst = &task.Description
+		case "due_date":
+			if null {
+				task.DueDate = nil
+				continue
+			}
+			dst = &task.DueDate
+		case "due_all_day":
+			dst = &task.DueAllDay
+		case "status":
+			dst = &task.Status
+		case "priority":
+			dst = &task.Priority
+		case "completed":
+			dst = &task.Completed
+		case "project_id":
+			dst = &task.ProjectID
+		case "parent_id":
+			if null {
+				continue // handled by PatchTask
+			}
+			dst = &task.ParentID
+		case "recurrence_rule":
+			if null {
+				task.RecurrenceRule = new(string)
+				continue
+			}
+			dst = &task.RecurrenceRule
+		case "label_ids":
+			if null {
+				task.LabelIDs = []int32{}
+				continue
+			}
+			dst = &task.LabelIDs
+		case "id", "user_id", "workspace_id", "assignee_id", "series_id", "completed_at", "created_at", "updated_at", "version", "labels", "subtasks", "children":
+			return nil, fmt.Errorf("%w: field %q is read-only", models.ErrInvalidInput, name)
+		default:
+			return nil, fmt.Errorf("%w: unknown field %q", models.ErrInvalidInput, name)
+		}
+
+		if null {
+			return nil, fmt.Errorf("%w: field %q cannot be null", models.ErrInvalidInput, name)
+		}
+		if err := json.Unmarshal(raw, dst); err != nil {
+			return nil, fmt.Errorf("%w: invalid value for %q: %v", models.ErrInvalidInput, name, err)
+		}
+	}
+
+	// A patch touching only the legacy flag lets applyTaskStatus derive the status from it.
+	if _, ok := members["status"]; !ok {
+		if _, ok := members["completed"]; ok {
+			task.Status = ""
+		}
+	}
+	return task, nil
+}
+
+// isJSONNull reports whether raw is the JSON literal null.
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
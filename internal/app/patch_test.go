@@ -0,0 +1,152 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestMergeTaskPatch(t *testing.T) {
+	due := time.Date(2026, time.May, 4, 9, 0, 0, 0, time.UTC)
+	projectID, parentID, rule := int32(3), int32(5), "FREQ=DAILY"
+	prev := &models.Task{
+		ID: 7, UserID: uuid.New(), WorkspaceID: 1, ProjectID: &projectID, ParentID: &parentID,
+		Title: "Report", Description: "Quarterly", DueDate: &due, Status: models.TaskStatusInProgress,
+		Priority: "high", RecurrenceRule: &rule, LabelIDs: []int32{1, 2},
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+		check func(t *testing.T, task *models.Task)
+	}{
+		{
+			name:  "absent members keep their values",
+			patch: `{"title": "Annual report"}`,
+			check: func(t *testing.T, task *models.Task) {
+				if task.Title != "Annual report" || task.Description != prev.Description || task.DueDate != prev.DueDate ||
+					task.Status != prev.Status || task.Priority != prev.Priority {
+					t.Errorf("task = %+v, want only the title changed", task)
+				}
+				if task.ProjectID != nil || task.ParentID != nil || task.RecurrenceRule != nil || task.LabelIDs != nil {
+					t.Error("absent pointer members must stay nil so that the update keeps them")
+				}
+			},
+		},
+		{
+			name:  "null due date removes the deadline",
+			patch: `{"due_date": null}`,
+			check: func(t *testing.T, task *models.Task) {
+				if task.DueDate != nil {
+					t.Errorf("due date = %v, want none", task.DueDate)
+				}
+			},
+		},
+		{
+			name:  "null parent is left to PatchTask",
+			patch: `{"parent_id": null}`,
+			check: func(t *testing.T, task *models.Task) {
+				if task.ParentID != nil {
+					t.Errorf("parent = %d, want nil", *task.ParentID)
+				}
+			},
+		},
+		{
+			name:  "null recurrence rule stops the series",
+			patch: `{"recurrence_rule": null}`,
+			check: func(t *testing.T, task *models.Task) {
+				if task.RecurrenceRule == nil || *task.RecurrenceRule != "" {
+					t.Errorf("recurrence rule = %v, want empty", task.RecurrenceRule)
+				}
+			},
+		},
+		{
+			name:  "null label IDs detach all labels",
+			patch: `{"label_ids": null}`,
+			check: func(t *testing.T, task *models.Task) {
+				if task.LabelIDs == nil || len(task.LabelIDs) != 0 {
+					t.Errorf("label IDs = %v, want an empty list", task.LabelIDs)
+				}
+			},
+		},
+		{
+			name:  "values are decoded",
+			patch: `{"project_id": 4, "parent_id": 6, "label_ids": [2, 3], "priority": "low"}`,
+			check: func(t *testing.T, task *models.Task) {
+				if task.ProjectID == nil || *task.ProjectID != 4 || task.ParentID == nil || *task.ParentID != 6 {
+					t.Errorf("project = %v, parent = %v, want 4 and 6", task.ProjectID, task.ParentID)
+				}
+				if !slices.Equal(task.LabelIDs, []int32{2, 3}) || task.Priority != "low" {
+					t.Errorf("labels = %v, priority = %q", task.LabelIDs, task.Priority)
+				}
+			},
+		},
+		{
+			name:  "completed alone derives the status",
+			patch: `{"completed": true}`,
+			check: func(t *testing.T, task *models.Task) {
+				if !task.Completed || task.Status != "" {
+					t.Errorf("completed = %v, status = %q, want the status left to applyTaskStatus", task.Completed, task.Status)
+				}
+			},
+		},
+		{
+			name:  "status wins over completed",
+			patch: `{"completed": true, "status": "todo"}`,
+			check: func(t *testing.T, task *models.Task) {
+				if task.Status != models.TaskStatusTodo {
+					t.Errorf("status = %q, want todo", task.Status)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var members map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.patch), &members); err != nil {
+				t.Fatal(err)
+			}
+			task, err := mergeTaskPatch(prev, members)
+			if err != nil {
+				t.Fatalf("mergeTaskPatch: %v", err)
+			}
+			tt.check(t, task)
+		})
+	}
+}
+
+func TestMergeTaskPatchRejects(t *testing.T) {
+	prev := &models.Task{ID: 7, Title: "Report", Status: models.TaskStatusTodo}
+	for _, patch := range []string{
+		`{"title": null}`,
+		`{"status": null}`,
+		`{"title": 42}`,
+		`{"workspace_id": 2}`,
+		`{"assignee_id": null}`,
+		`{"version": 3}`,
+		`{"colour": "red"}`,
+	} {
+		t.Run(patch, func(t *testing.T) {
+			var members map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(patch), &members); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := mergeTaskPatch(prev, members); !errors.Is(err, models.ErrInvalidInput) {
+				t.Errorf("err = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestIsJSONNull(t *testing.T) {
+	for raw, want := range map[string]bool{"null": true, " null\n": true, `"null"`: false, "0": false, "{}": false} {
+		if got := isJSONNull(json.RawMessage(raw)); got != want {
+			t.Errorf("isJSONNull(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// CreateProject creates a new project in a workspace the user may edit, by default their personal one.
+func (s *TaskManagerService) CreateProject(ctx context.Context, project *models.Project, userID uuid.UUID) error {
+	s.Log.Debug("Starting CreateProject", slog.String("userID", userID.String()))
+	project.UserID = userID
+	project.IsInbox = false
+	if err := project.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if project.WorkspaceID, err = s.editableWorkspaceTx(ctx, tx, project.WorkspaceID, userID); err != nil {
+		return err
+	}
+	if err = s.db.CreateProjectTx(ctx, tx, project); err != nil {
+		return fmt.Errorf("failed to create project: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Project created successfully", slog.Int("projectID", int(project.ID)))
+	return nil
+}
+
+// GetProject retrieves a project by its ID.
+func (s *TaskManagerService) GetProject(ctx context.Context, id int32, userID uuid.UUID) (*models.Project, error) {
+	s.Log.Debug("Starting GetProject", slog.Int("projectID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	project, err := s.db.GetProjectTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return project, nil
+}
+
+// ListProjects returns the projects of the user's workspaces, optionally including archived ones.
+// A non-nil workspaceID restricts the list to that workspace.
+func (s *TaskManagerService) ListProjects(ctx context.Context, userID uuid.UUID, workspaceID *int32, includeArchived bool) ([]*models.Project, error) {
+	s.Log.Debug("Starting ListProjects", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	projects, err := s.db.ListProjectsTx(ctx, tx, userID, workspaceID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Projects listed successfully", slog.Int("count", len(projects)))
+	return projects, nil
+}
+
+// UpdateProject updates the name and description of a project.
+func (s *TaskManagerService) UpdateProject(ctx context.Context, project *models.Project) error {
+	s.Log.Debug("Starting UpdateProject", slog.Int("projectID", int(project.ID)))
+	if err := project.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if _, err = s.editableProjectTx(ctx, tx, project.ID, project.UserID); err != nil {
+		return err
+	}
+	if err = s.db.UpdateProjectTx(ctx, tx, project); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("project with id %d not found: %w", project.ID, err)
+		}
+		return fmt.Errorf("failed to update project: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Project updated successfully", slog.Int("projectID", int(project.ID)))
+	return nil
+}
+
+// SetProjectArchived archives or unarchives a project. The Inbox cannot be archived.
+func (s *TaskManagerService) SetProjectArchived(ctx context.Context, id int32, userID uuid.UUID, archived bool) (*models.Project, error) {
+	s.Log.Debug("Starting SetProjectArchived", slog.Int("projectID", int(id)), slog.Bool("archived", archived))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	current, err := s.editableProjectTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current.IsInbox && archived {
+		err = fmt.Errorf("%w: the inbox project cannot be archived", models.ErrInvalidInput)
+		return nil, err
+	}
+
+	project, err := s.db.SetProjectArchivedTx(ctx, tx, id, userID, archived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive project: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Project archive state changed", slog.Int("projectID", int(id)))
+	return project, nil
+}
+
+// DeleteProject deletes a project. Depending on mode its tasks are either
+// moved to the trash or to the Inbox of its workspace. Tasks in the trash move to the Inbox
+// as well, so that they return there when restored. The Inbox itself cannot be deleted.
+func (s *TaskManagerService) DeleteProject(ctx context.Context, id int32, userID uuid.UUID, mode string) error {
+	s.Log.Debug("Starting DeleteProject", slog.Int("projectID", int(id)), slog.String("mode", mode))
+	if mode == "" {
+		mode = models.ProjectDeleteMoveToInbox
+	}
+	if mode != models.ProjectDeleteMoveToInbox && mode != models.ProjectDeleteCascade {
+		return fmt.Errorf("%w: unknown delete mode %q", models.ErrInvalidInput, mode)
+	}
+
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	project, err := s.editableProjectTx(ctx, tx, id, userID)
+	if err != nil {
+		return err
+	}
+	if project.IsInbox {
+		err = fmt.Errorf("%w: the inbox project cannot be deleted", models.ErrInvalidInput)
+		return err
+	}
+
+	if mode == models.ProjectDeleteCascade {
+		if err = s.db.DeleteProjectTasksTx(ctx, tx, id, userID); err != nil {
+			return err
+		}
+	}
+	var inbox *models.Project
+	if inbox, err = s.ensureInboxTx(ctx, tx, project.WorkspaceID, userID); err != nil {
+		return err
+	}
+	if err = s.db.MoveProjectTasksTx(ctx, tx, id, inbox.ID, userID); err != nil {
+		return err
+	}
+
+	if err = s.db.DeleteProjectTx(ctx, tx, id, userID); err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Project deleted successfully", slog.Int("projectID", int(id)))
+	return nil
+}
+
+// MoveTask moves a task into another project of its workspace, or out of any project when projectID is nil.
+func (s *TaskManagerService) MoveTask(ctx context.Context, taskID int32, projectID *int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting MoveTask", slog.Int("taskID", int(taskID)))
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if projectID != nil {
+		var task *models.Task
+		if task, err = s.db.GetTaskTx(ctx, tx, taskID, userID); err != nil {
+			return fmt.Errorf("failed to get task: %w", err)
+		}
+		if task == nil {
+			err = fmt.Errorf("task with id %d not found: %w", taskID, sql.ErrNoRows)
+			return err
+		}
+		if err = s.checkTaskProjectTx(ctx, tx, *projectID, task.WorkspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	if err = s.db.MoveTaskTx(ctx, tx, taskID, projectID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.taskNotEditableTx(ctx, tx, taskID, userID)
+		}
+		return fmt.Errorf("failed to move task: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Task moved successfully", slog.Int("taskID", int(taskID)))
+	return nil
+}
+
+// checkTaskProjectTx verifies that tasks of a workspace can be placed into the project:
+// it must be a project of that workspace visible to the user and must not be archived.
+func (s *TaskManagerService) checkTaskProjectTx(ctx context.Context, tx *sql.Tx, projectID, workspaceID int32, userID uuid.UUID) error {
+	project, err := s.db.GetProjectTx(ctx, tx, projectID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get project: %w", err)
+	}
+	if project == nil {
+		return fmt.Errorf("%w: project %d does not exist", models.ErrInvalidInput, projectID)
+	}
+	if project.WorkspaceID != workspaceID {
+		return fmt.Errorf("%w: project %d is in another workspace", models.ErrInvalidInput, projectID)
+	}
+	if project.ArchivedAt != nil {
+		return fmt.Errorf("%w: project %d is archived", models.ErrInvalidInput, projectID)
+	}
+	return nil
+}
+
+// defaultProjectTx returns the project new tasks go to when none is given: the default project
+// from the user's settings while it is not archived and the user may still edit its workspace,
+// the Inbox of the personal workspace otherwise. A non-zero workspaceID restricts the choice to
+// that workspace, falling back to its Inbox.
+func (s *TaskManagerService) defaultProjectTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, workspaceID int32) (*models.Project, error) {
+	settings, err := s.db.GetUserSettingsTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil && settings.DefaultProjectID != nil {
+		project, err := s.db.GetProjectTx(ctx, tx, *settings.DefaultProjectID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project: %w", err)
+		}
+		if project != nil && project.ArchivedAt == nil && (workspaceID == 0 || project.WorkspaceID == workspaceID) {
+			role, err := s.db.GetWorkspaceRoleTx(ctx, tx, project.WorkspaceID, userID, false)
+			if err != nil {
+				return nil, err
+			}
+			if models.CanEditWorkspaceContent(role) {
+				return project, nil
+			}
+		}
+	}
+
+	if workspaceID == 0 {
+		personal, err := s.ensurePersonalWorkspaceTx(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		workspaceID = personal.ID
+	}
+	return s.ensureInboxTx(ctx, tx, workspaceID, userID)
+}
+
+// editableWorkspaceTx resolves the workspace new content goes to: the given one when the user
+// may edit it, the user's personal workspace when workspaceID is zero.
+func (s *TaskManagerService) editableWorkspaceTx(ctx context.Context, tx *sql.Tx, workspaceID int32, userID uuid.UUID) (int32, error) {
+	if workspaceID == 0 {
+		personal, err := s.ensurePersonalWorkspaceTx(ctx, tx, userID)
+		if err != nil {
+			return 0, err
+		}
+		return personal.ID, nil
+	}
+	if _, err := s.requireWorkspaceRoleTx(ctx, tx, workspaceID, userID, models.CanEditWorkspaceContent); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%w: workspace %d does not exist", models.ErrInvalidInput, workspaceID)
+		}
+		return 0, err
+	}
+	return workspaceID, nil
+}
+
+// editableProjectTx returns a project the user may change, failing with sql.ErrNoRows when it is
+// not visible to the user and with models.ErrForbidden when the user only views its workspace.
+func (s *TaskManagerService) editableProjectTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Project, error) {
+	project, err := s.db.GetProjectTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	if project == nil {
+		return nil, fmt.Errorf("project with id %d not found: %w", id, sql.ErrNoRows)
+	}
+	if _, err := s.requireWorkspaceRoleTx(ctx, tx, project.WorkspaceID, userID, models.CanEditWorkspaceContent); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// ensureInboxTx returns the Inbox project of a workspace, creating it for the user if it is missing.
+func (s *TaskManagerService) ensureInboxTx(ctx context.Context, tx *sql.Tx, workspaceID int32, userID uuid.UUID) (*models.Project, error) {
+	inbox, err := s.db.GetInboxProjectTx(ctx, tx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if inbox != nil {
+		return inbox, nil
+	}
+
+	inbox = &models.Project{UserID: userID, WorkspaceID: workspaceID, Name: models.InboxProjectName, IsInbox: true}
+	if err := s.db.CreateProjectTx(ctx, tx, inbox); err != nil {
+		return nil, fmt.Errorf("failed to create inbox project: %w", err)
+	}
+	return inbox, nil
+}
@@ -0,0 +1,302 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+	"github.com/teambition/rrule-go"
+)
+
+// MaxOccurrencePreview caps the number of occurrences returned by PreviewOccurrences.
+const MaxOccurrencePreview = 100
+
+// PreviewOccurrences returns the due dates of the next n occurrences after the given task.
+func (s *TaskManagerService) PreviewOccurrences(ctx context.Context, id int32, userID uuid.UUID, n int) ([]time.Time, error) {
+	s.Log.Debug("Starting PreviewOccurrences", slog.Int("taskID", int(id)), slog.Int("count", n))
+	if n <= 0 || n > MaxOccurrencePreview {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", models.ErrInvalidInput, MaxOccurrencePreview)
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	task, err := s.db.GetTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if task == nil {
+		err = fmt.Errorf("task with id %d not found: %w", id, sql.ErrNoRows)
+		return nil, err
+	}
+
+	series, err := s.activeSeriesTx(ctx, tx, task, userID)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		err = fmt.Errorf("%w: task %d does not repeat", models.ErrInvalidInput, id)
+		return nil, err
+	}
+
+	dtstart, err := s.seriesStartTx(ctx, tx, series, task.DueAllDay)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := parseRecurrenceRule(series.RecurrenceRule, dtstart)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	after := series.DTStart
+	if task.DueDate != nil {
+		after = *task.DueDate
+	}
+
+	next := rule.Iterator()
+	occurrences := make([]time.Time, 0, n)
+	for len(occurrences) < n {
+		t, ok := next()
+		if !ok {
+			break
+		}
+		if t.After(after) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences, nil
+}
+
+// startSeriesTx creates a series for a new task that has a recurrence rule.
+func (s *TaskManagerService) startSeriesTx(ctx context.Context, tx *sql.Tx, task *models.Task) error {
+	if task.RecurrenceRule == nil || strings.TrimSpace(*task.RecurrenceRule) == "" {
+		task.SeriesID = nil
+		return nil
+	}
+
+	series, err := s.newSeries(task, *task.RecurrenceRule)
+	if err != nil {
+		return err
+	}
+	if err := s.db.CreateTaskSeriesTx(ctx, tx, series); err != nil {
+		return err
+	}
+	task.SeriesID = &series.ID
+	return nil
+}
+
+// updateSeriesTx applies recurrence changes of an updated occurrence to its series.
+// A changed rule always affects the whole series and is re-anchored at the task's due date,
+// an empty rule ends the series. With the series scope the title and description become
+// the template for future occurrences and are copied onto the other open ones.
+func (s *TaskManagerService) updateSeriesTx(ctx context.Context, tx *sql.Tx, prev, task *models.Task, scope string) error {
+	switch scope {
+	case "", models.TaskScopeThis, models.TaskScopeSeries:
+	default:
+		return fmt.Errorf("%w: unknown scope %q", models.ErrInvalidInput, scope)
+	}
+
+	task.SeriesID = prev.SeriesID
+	series, err := s.activeSeriesTx(ctx, tx, prev, task.UserID)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if task.RecurrenceRule != nil {
+		rule := normalizeRecurrenceRule(*task.RecurrenceRule)
+		switch {
+		case rule == "" && series != nil:
+			now := time.Now()
+			series.EndedAt = &now
+			changed = true
+		case rule != "" && series == nil:
+			if series, err = s.newSeries(task, rule); err != nil {
+				return err
+			}
+			if err := s.db.CreateTaskSeriesTx(ctx, tx, series); err != nil {
+				return err
+			}
+			task.SeriesID = &series.ID
+		case rule != "" && rule != series.RecurrenceRule:
+			if task.DueDate == nil {
+				return fmt.Errorf("%w: a recurring task needs a due date", models.ErrInvalidInput)
+			}
+			if _, err := parseRecurrenceRule(rule, *task.DueDate); err != nil {
+				return err
+			}
+			series.RecurrenceRule = rule
+			series.DTStart = *task.DueDate
+			changed = true
+		}
+	}
+
+	if scope == models.TaskScopeSeries && series != nil && series.EndedAt == nil {
+		series.Title = task.Title
+		series.Description = task.Description
+		changed = true
+		if err := s.db.UpdateOpenOccurrencesTx(ctx, tx, series.ID, task.UserID, task.ID, task.Title, task.Description); err != nil {
+			return err
+		}
+	}
+
+	if changed {
+		if err := s.db.UpdateTaskSeriesTx(ctx, tx, series, task.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleNextOccurrenceTx creates the occurrence following a just completed task of a series.
+// An occurrence whose due date was cleared is followed by the first date after now.
+// The series ends when its rule yields no further dates. The occurrence is created by task.UserID,
+// the user completing the task, and keeps the assignee and shares of the completed one.
+func (s *TaskManagerService) scheduleNextOccurrenceTx(ctx context.Context, tx *sql.Tx, task *models.Task) error {
+	series, err := s.activeSeriesTx(ctx, tx, task, task.UserID)
+	if err != nil || series == nil {
+		return err
+	}
+
+	dtstart, err := s.seriesStartTx(ctx, tx, series, task.DueAllDay)
+	if err != nil {
+		return err
+	}
+	rule, err := parseRecurrenceRule(series.RecurrenceRule, dtstart)
+	if err != nil {
+		return err
+	}
+	after := time.Now()
+	if task.DueDate != nil {
+		after = *task.DueDate
+	}
+	due := rule.After(after, false)
+	if due.IsZero() {
+		now := time.Now()
+		series.EndedAt = &now
+		return s.db.UpdateTaskSeriesTx(ctx, tx, series, task.UserID)
+	}
+
+	exists, err := s.db.OccurrenceExistsTx(ctx, tx, series.ID, due)
+	if err != nil || exists {
+		return err
+	}
+
+	next := &models.Task{
+		UserID:      task.UserID,
+		WorkspaceID: task.WorkspaceID,
+		AssigneeID:  task.AssigneeID,
+		ProjectID:   task.ProjectID,
+		ParentID:    task.ParentID,
+		SeriesID:    &series.ID,
+		Title:       series.Title,
+		Description: series.Description,
+		DueDate:     &due,
+		DueAllDay:   task.DueAllDay,
+		Status:      models.TaskStatusTodo,
+		Priority:    task.Priority,
+	}
+	id, err := s.db.CreateTaskTx(ctx, tx, next)
+	if err != nil {
+		return fmt.Errorf("failed to create next occurrence: %w", err)
+	}
+	if err := s.db.CopyTaskLabelsTx(ctx, tx, task.ID, id); err != nil {
+		return err
+	}
+	if err := s.db.CopyTaskSharesTx(ctx, tx, task.ID, id); err != nil {
+		return err
+	}
+	s.Log.Debug("Next occurrence scheduled", slog.Int("taskID", int(id)), slog.Time("due", due))
+	return nil
+}
+
+// seriesStartTx returns the start of a series in the time zone its dates repeat in. Timed tasks
+// repeat in the zone of the series' creator, so they keep their wall-clock time across DST changes.
+// All-day tasks are due on calendar days stored as midnight UTC, and repeat in UTC.
+func (s *TaskManagerService) seriesStartTx(ctx context.Context, tx *sql.Tx, series *models.TaskSeries, allDay bool) (time.Time, error) {
+	if allDay {
+		return series.DTStart.UTC(), nil
+	}
+	loc, err := s.userLocationTx(ctx, tx, series.UserID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return series.DTStart.In(loc), nil
+}
+
+// activeSeriesTx returns the series of the task unless it has ended, as visible to the user.
+func (s *TaskManagerService) activeSeriesTx(ctx context.Context, tx *sql.Tx, task *models.Task, userID uuid.UUID) (*models.TaskSeries, error) {
+	if task.SeriesID == nil {
+		return nil, nil
+	}
+	series, err := s.db.GetTaskSeriesTx(ctx, tx, *task.SeriesID, userID)
+	if err != nil || series == nil || series.EndedAt != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// newSeries builds a series anchored at the task's due date, using the task as the template.
+func (s *TaskManagerService) newSeries(task *models.Task, rule string) (*models.TaskSeries, error) {
+	rule = normalizeRecurrenceRule(rule)
+	if task.DueDate == nil {
+		return nil, fmt.Errorf("%w: a recurring task needs a due date", models.ErrInvalidInput)
+	}
+	if _, err := parseRecurrenceRule(rule, *task.DueDate); err != nil {
+		return nil, err
+	}
+	return &models.TaskSeries{
+		ID:             uuid.New(),
+		UserID:         task.UserID,
+		WorkspaceID:    task.WorkspaceID,
+		RecurrenceRule: rule,
+		DTStart:        *task.DueDate,
+		Title:          task.Title,
+		Description:    task.Description,
+	}, nil
+}
+
+// normalizeRecurrenceRule trims the rule and drops an optional "RRULE:" prefix.
+func normalizeRecurrenceRule(rule string) string {
+	return strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+}
+
+// parseRecurrenceRule parses an RFC 5545 RRULE anchored at dtstart, expanding it in the location of dtstart.
+// The start is always taken from the task, so DTSTART inside the rule is rejected.
+func parseRecurrenceRule(rule string, dtstart time.Time) (*rrule.RRule, error) {
+	rule = normalizeRecurrenceRule(rule)
+	if strings.ContainsAny(rule, "\r\n") || strings.Contains(strings.ToUpper(rule), "DTSTART") {
+		return nil, fmt.Errorf("%w: recurrence rule must be a single RRULE without DTSTART", models.ErrInvalidInput)
+	}
+
+	opt, err := rrule.StrToROption(rule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid recurrence rule: %v", models.ErrInvalidInput, err)
+	}
+	opt.Dtstart = dtstart
+
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid recurrence rule: %v", models.ErrInvalidInput, err)
+	}
+	return r, nil
+}
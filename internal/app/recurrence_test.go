@@ -0,0 +1,229 @@
+package app
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata" // Europe/Berlin for the DST tests
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestParseRecurrenceRule(t *testing.T) {
+	dtstart := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		rule    string
+		want    []time.Time
+		wantErr bool
+	}{
+		{
+			name: "daily count",
+			rule: "FREQ=DAILY;COUNT=3",
+			want: []time.Time{dtstart, dtstart.AddDate(0, 0, 1), dtstart.AddDate(0, 0, 2)},
+		},
+		{
+			name: "RRULE prefix and spaces",
+			rule: "  RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=2 ",
+			want: []time.Time{dtstart, dtstart.AddDate(0, 0, 14)},
+		},
+		{name: "DTSTART in the rule", rule: "DTSTART:20260101T000000Z;FREQ=DAILY", wantErr: true},
+		{name: "several lines", rule: "FREQ=DAILY\nRRULE:FREQ=WEEKLY", wantErr: true},
+		{name: "unknown frequency", rule: "FREQ=SOMETIMES", wantErr: true},
+		{name: "empty rule", rule: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRecurrenceRule(tt.rule, dtstart)
+			if tt.wantErr {
+				if !errors.Is(err, models.ErrInvalidInput) {
+					t.Fatalf("err = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRecurrenceRule: %v", err)
+			}
+			got := rule.All()
+			if len(got) != len(tt.want) {
+				t.Fatalf("occurrences = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestParseRecurrenceRuleKeepsLocalTime checks that a rule anchored in a time zone keeps
+// its wall-clock time when the zone changes to daylight saving time.
+func TestParseRecurrenceRuleKeepsLocalTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Mondays at 09:00 around the change to CEST on 2026-03-29.
+	rule, err := parseRecurrenceRule("FREQ=WEEKLY;COUNT=3", time.Date(2026, time.March, 23, 9, 0, 0, 0, berlin))
+	if err != nil {
+		t.Fatalf("parseRecurrenceRule: %v", err)
+	}
+	for _, occurrence := range rule.All() {
+		if local := occurrence.In(berlin); local.Hour() != 9 || local.Minute() != 0 {
+			t.Errorf("occurrence %v is at %s local time, want 09:00", occurrence, local.Format("15:04"))
+		}
+	}
+}
+
+// seriesQuery answers the series lookup with a series of the given rule owned by ownerID.
+func seriesQuery(seriesID, ownerID uuid.UUID, rule string, dtstart time.Time) fakeQuery {
+	return fakeQuery{
+		match:   "FROM task_series WHERE id = $1",
+		columns: []string{"id", "user_id", "workspace_id", "recurrence_rule", "dtstart", "title", "description", "ended_at", "created_at", "updated_at"},
+		rows:    [][]driver.Value{{seriesID.String(), ownerID.String(), int64(1), rule, dtstart, "Standup", "", nil, dtstart, dtstart}},
+	}
+}
+
+// userQuery answers the user lookup with a user living in the given time zone.
+func userQuery(userID uuid.UUID, timeZone string) fakeQuery {
+	return fakeQuery{
+		match:   "FROM users WHERE id = $1",
+		columns: []string{"id", "kratos_id", "email", "display_name", "time_zone", "created_at", "updated_at"},
+		rows:    [][]driver.Value{{userID.String(), "kratos-id", "jane@example.com", "Jane", timeZone, time.Time{}, time.Time{}}},
+	}
+}
+
+// occurrenceQueries answer the statements creating a new occurrence; exists tells whether it was already created.
+func occurrenceQueries(exists bool) []fakeQuery {
+	return []fakeQuery{
+		{match: "SELECT EXISTS (SELECT 1 FROM tasks WHERE series_id", columns: []string{"exists"}, rows: [][]driver.Value{{exists}}},
+		{match: "INSERT INTO tasks", columns: []string{"id", "version"}, rows: [][]driver.Value{{int64(42), int64(1)}}},
+		{match: "INSERT INTO task_labels"},
+		{match: "INSERT INTO task_shares"},
+	}
+}
+
+func TestScheduleNextOccurrence(t *testing.T) {
+	ownerID, seriesID := uuid.New(), uuid.New()
+	tests := []struct {
+		name     string
+		rule     string
+		dtstart  time.Time
+		allDay   bool
+		timeZone string
+		exists   bool
+		wantDue  time.Time
+	}{
+		{
+			name:     "timed task keeps the owner's local time across DST",
+			rule:     "FREQ=WEEKLY",
+			dtstart:  time.Date(2026, time.March, 23, 8, 0, 0, 0, time.UTC), // 09:00 CET
+			timeZone: "Europe/Berlin",
+			wantDue:  time.Date(2026, time.March, 30, 7, 0, 0, 0, time.UTC), // 09:00 CEST
+		},
+		{
+			name:     "all-day task stays on midnight UTC",
+			rule:     "FREQ=WEEKLY",
+			dtstart:  time.Date(2026, time.March, 23, 0, 0, 0, 0, time.UTC),
+			allDay:   true,
+			timeZone: "Europe/Berlin",
+			wantDue:  time.Date(2026, time.March, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "owner without time zone",
+			rule:     "FREQ=DAILY;INTERVAL=2",
+			dtstart:  time.Date(2026, time.March, 23, 8, 0, 0, 0, time.UTC),
+			timeZone: "Local",
+			wantDue:  time.Date(2026, time.March, 25, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "occurrence already created",
+			rule:     "FREQ=WEEKLY",
+			dtstart:  time.Date(2026, time.March, 23, 8, 0, 0, 0, time.UTC),
+			timeZone: "UTC",
+			exists:   true,
+			wantDue:  time.Date(2026, time.March, 30, 8, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := append([]fakeQuery{seriesQuery(seriesID, ownerID, tt.rule, tt.dtstart), userQuery(ownerID, tt.timeZone)}, occurrenceQueries(tt.exists)...)
+			s, fake := newTestService(t, queries...)
+
+			due := tt.dtstart
+			task := &models.Task{ID: 7, UserID: ownerID, WorkspaceID: 1, SeriesID: &seriesID, DueDate: &due, DueAllDay: tt.allDay}
+			if err := s.scheduleNextOccurrenceTx(t.Context(), beginTx(t, s), task); err != nil {
+				t.Fatalf("scheduleNextOccurrenceTx: %v", err)
+			}
+
+			checks := fake.called("SELECT EXISTS")
+			if len(checks) != 1 {
+				t.Fatalf("occurrence checked %d times, want once", len(checks))
+			}
+			if got := checks[0].args[1].(time.Time); !got.Equal(tt.wantDue) {
+				t.Errorf("next due date = %v, want %v", got, tt.wantDue)
+			}
+
+			inserts := fake.called("INSERT INTO tasks")
+			if tt.exists {
+				if len(inserts) != 0 {
+					t.Errorf("created %d occurrences, want none for an existing one", len(inserts))
+				}
+				return
+			}
+			if len(inserts) != 1 {
+				t.Fatalf("created %d occurrences, want one", len(inserts))
+			}
+			if got := inserts[0].args[2].(time.Time); !got.Equal(tt.wantDue) {
+				t.Errorf("created occurrence due %v, want %v", got, tt.wantDue)
+			}
+			if got := inserts[0].args[3].(bool); got != tt.allDay {
+				t.Errorf("created occurrence all-day = %v, want %v", got, tt.allDay)
+			}
+			if len(fake.called("INSERT INTO task_labels")) != 1 || len(fake.called("INSERT INTO task_shares")) != 1 {
+				t.Error("labels and shares were not copied to the new occurrence")
+			}
+		})
+	}
+}
+
+func TestScheduleNextOccurrenceEndsSeries(t *testing.T) {
+	ownerID, seriesID := uuid.New(), uuid.New()
+	dtstart := time.Date(2026, time.March, 23, 8, 0, 0, 0, time.UTC)
+	s, fake := newTestService(t,
+		seriesQuery(seriesID, ownerID, "FREQ=DAILY;COUNT=2", dtstart),
+		userQuery(ownerID, "UTC"),
+		fakeQuery{match: "UPDATE task_series", rowsAffected: 1},
+	)
+
+	due := dtstart.AddDate(0, 0, 1) // The last occurrence.
+	task := &models.Task{ID: 7, UserID: ownerID, WorkspaceID: 1, SeriesID: &seriesID, DueDate: &due}
+	if err := s.scheduleNextOccurrenceTx(t.Context(), beginTx(t, s), task); err != nil {
+		t.Fatalf("scheduleNextOccurrenceTx: %v", err)
+	}
+
+	updates := fake.called("UPDATE task_series")
+	if len(updates) != 1 {
+		t.Fatalf("series updated %d times, want once", len(updates))
+	}
+	if endedAt, ok := updates[0].args[4].(time.Time); !ok || endedAt.IsZero() {
+		t.Errorf("ended_at = %v, want the series to be ended", updates[0].args[4])
+	}
+	if len(fake.called("INSERT INTO tasks")) != 0 {
+		t.Error("created an occurrence after the last one")
+	}
+}
+
+func TestScheduleNextOccurrenceWithoutSeries(t *testing.T) {
+	s, fake := newTestService(t)
+	due := time.Now()
+	if err := s.scheduleNextOccurrenceTx(t.Context(), beginTx(t, s), &models.Task{ID: 7, DueDate: &due}); err != nil {
+		t.Fatalf("scheduleNextOccurrenceTx: %v", err)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("ran %d statements for a task without series", len(fake.calls))
+	}
+}
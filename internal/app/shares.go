@@ -0,0 +1,286 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// SetTaskAssignee hands a task to a user, or unassigns it when assigneeID is nil.
+// The user must own the task or edit its workspace. The assignee must be a member of the
+// workspace or a user the task is shared with.
+func (s *TaskManagerService) SetTaskAssignee(ctx context.Context, id int32, assigneeID *uuid.UUID, userID uuid.UUID) error {
+	s.Log.Debug("Starting SetTaskAssignee", slog.Int("taskID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.setTaskAssigneeTx(ctx, tx, id, assigneeID, userID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Task assignee changed successfully", slog.Int("taskID", int(id)))
+	return nil
+}
+
+// setTaskAssigneeTx changes the assignee of a task on behalf of userID. Assignees and share
+// holders who only edit the task cannot hand it on.
+func (s *TaskManagerService) setTaskAssigneeTx(ctx context.Context, tx *sql.Tx, id int32, assigneeID *uuid.UUID, userID uuid.UUID) error {
+	access, err := s.db.GetTaskAccessTx(ctx, tx, id, userID)
+	if err != nil {
+		return err
+	}
+	task, err := s.db.GetTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if access == "" || task == nil {
+		return fmt.Errorf("task with id %d not found: %w", id, sql.ErrNoRows)
+	}
+	if access != models.TaskAccessOwner {
+		role, err := s.db.GetWorkspaceRoleTx(ctx, tx, task.WorkspaceID, userID, false)
+		if err != nil {
+			return err
+		}
+		if !models.CanEditWorkspaceContent(role) {
+			return fmt.Errorf("changing the assignee of task %d requires owning it or editing its workspace: %w", id, models.ErrForbidden)
+		}
+	}
+
+	if err := s.checkAssigneeTx(ctx, tx, task.WorkspaceID, id, assigneeID); err != nil {
+		return err
+	}
+	if err := s.db.SetTaskAssigneeTx(ctx, tx, id, assigneeID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.taskNotEditableTx(ctx, tx, id, userID)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkAssigneeTx rejects assignees who are neither members of the task's workspace nor
+// users the task is shared with. taskID is 0 for tasks not created yet, which have no shares.
+func (s *TaskManagerService) checkAssigneeTx(ctx context.Context, tx *sql.Tx, workspaceID, taskID int32, assigneeID *uuid.UUID) error {
+	if assigneeID == nil {
+		return nil
+	}
+	role, err := s.db.GetWorkspaceRoleTx(ctx, tx, workspaceID, *assigneeID, false)
+	if err != nil {
+		return err
+	}
+	if role != "" {
+		return nil
+	}
+	if taskID != 0 {
+		shared, err := s.db.IsTaskSharedWithTx(ctx, tx, taskID, *assigneeID)
+		if err != nil {
+			return err
+		}
+		if shared {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: user %s is not a member of the workspace and the task is not shared with them", models.ErrInvalidInput, *assigneeID)
+}
+
+// checkTaskOnlyUpdate limits updates by users who edit a task as its assignee or through
+// a share without editing its workspace. They cannot move the task to another project or
+// parent, change its recurrence or labels, or edit its whole series. Unchanged values
+// are cleared from task so that the update keeps them without workspace checks.
+func (s *TaskManagerService) checkTaskOnlyUpdate(ctx context.Context, tx *sql.Tx, prev, task *models.Task, opts models.TaskUpdateOptions) error {
+	if err := s.db.LoadTaskLabelsTx(ctx, tx, prev); err != nil {
+		return err
+	}
+	if err := s.db.LoadTaskRecurrenceTx(ctx, tx, prev); err != nil {
+		return err
+	}
+
+	labelIDs := make([]int32, 0, len(prev.Labels))
+	for _, label := range prev.Labels {
+		labelIDs = append(labelIDs, label.ID)
+	}
+	sameIDs := func(a, b []int32) bool {
+		a, b = slices.Clone(a), slices.Clone(b)
+		slices.Sort(a)
+		slices.Sort(b)
+		return slices.Equal(slices.Compact(a), slices.Compact(b))
+	}
+
+	var field string
+	switch {
+	case task.ProjectID != nil && (prev.ProjectID == nil || *task.ProjectID != *prev.ProjectID):
+		field = "project"
+	case task.ParentID != nil && (prev.ParentID == nil || *task.ParentID != *prev.ParentID):
+		field = "parent"
+	case task.RecurrenceRule != nil && normalizeRecurrenceRule(*task.RecurrenceRule) != normalizeRecurrenceRule(derefString(prev.RecurrenceRule)):
+		field = "recurrence"
+	case task.LabelIDs != nil && !sameIDs(task.LabelIDs, labelIDs):
+		field = "labels"
+	case opts.Scope == models.TaskScopeSeries:
+		field = "series"
+	}
+	if field != "" {
+		return fmt.Errorf("changing the %s of task %d requires editing its workspace: %w", field, task.ID, models.ErrForbidden)
+	}
+
+	task.ProjectID, task.ParentID, task.RecurrenceRule, task.LabelIDs = nil, nil, nil, nil
+	return nil
+}
+
+// derefString returns the string p points to, or "" for nil.
+func derefString(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// ShareTask grants the user with share.Email access to a task. Sharing again with the
+// same user changes the permission. Only owners of the task may share it.
+func (s *TaskManagerService) ShareTask(ctx context.Context, share *models.TaskShare, userID uuid.UUID) error {
+	s.Log.Debug("Starting ShareTask", slog.Int("taskID", int(share.TaskID)), slog.String("userID", userID.String()))
+	if err := share.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, share.TaskID, userID, models.TaskAccessOwner); err != nil {
+		return err
+	}
+
+	user, err := s.db.GetUserByEmailTx(ctx, tx, share.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		err = fmt.Errorf("%w: no user with email %s", models.ErrInvalidInput, share.Email)
+		return err
+	}
+	if user.ID == userID {
+		err = fmt.Errorf("%w: a task cannot be shared with yourself", models.ErrInvalidInput)
+		return err
+	}
+
+	share.UserID, share.DisplayName, share.SharedBy = user.ID, user.DisplayName, &userID
+	if err = s.db.UpsertTaskShareTx(ctx, tx, share); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Info("Task shared", slog.Int("taskID", int(share.TaskID)), slog.String("permission", share.Permission))
+	return nil
+}
+
+// ListTaskShares returns the users a task is shared with. Anyone who can view the task may list them.
+func (s *TaskManagerService) ListTaskShares(ctx context.Context, taskID int32, userID uuid.UUID) ([]*models.TaskShare, error) {
+	s.Log.Debug("Starting ListTaskShares", slog.Int("taskID", int(taskID)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, models.TaskAccessView); err != nil {
+		return nil, err
+	}
+	shares, err := s.db.ListTaskSharesTx(ctx, tx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return shares, nil
+}
+
+// UnshareTask revokes the share of a task with a user. Owners of the task may revoke any share,
+// other users only their own.
+func (s *TaskManagerService) UnshareTask(ctx context.Context, taskID int32, memberID, userID uuid.UUID) error {
+	s.Log.Debug("Starting UnshareTask", slog.Int("taskID", int(taskID)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	required := models.TaskAccessOwner
+	if memberID == userID {
+		required = models.TaskAccessView
+	}
+	if err = s.requireTaskAccessTx(ctx, tx, taskID, userID, required); err != nil {
+		return err
+	}
+	if err = s.db.DeleteTaskShareTx(ctx, tx, taskID, memberID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("share with user %s not found: %w", memberID, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Info("Task unshared", slog.Int("taskID", int(taskID)))
+	return nil
+}
+
+// requireTaskAccessTx fails with sql.ErrNoRows when the user cannot see the task and
+// with models.ErrForbidden when the user's access is below required.
+func (s *TaskManagerService) requireTaskAccessTx(ctx context.Context, tx *sql.Tx, taskID int32, userID uuid.UUID, required string) error {
+	access, err := s.db.GetTaskAccessTx(ctx, tx, taskID, userID)
+	if err != nil {
+		return err
+	}
+	if access == "" {
+		return fmt.Errorf("task with id %d not found: %w", taskID, sql.ErrNoRows)
+	}
+	if !models.TaskAccessAllows(access, required) {
+		return fmt.Errorf("%s access to task %d: %w", access, taskID, models.ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestCheckAssignee(t *testing.T) {
+	assigneeID := uuid.New()
+	tests := []struct {
+		name    string
+		role    string
+		shared  bool
+		taskID  int32
+		wantErr bool
+	}{
+		{name: "workspace member", role: models.WorkspaceRoleMember, taskID: 7},
+		{name: "workspace viewer", role: models.WorkspaceRoleViewer, taskID: 7},
+		{name: "user the task is shared with", shared: true, taskID: 7},
+		{name: "stranger", taskID: 7, wantErr: true},
+		{name: "stranger on a new task", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles := fakeQuery{match: "SELECT role FROM workspace_members", columns: []string{"role"}}
+			if tt.role != "" {
+				roles.rows = [][]driver.Value{{tt.role}}
+			}
+			s, fake := newTestService(t, roles,
+				fakeQuery{match: "FROM task_shares WHERE task_id = $1 AND user_id = $2", columns: []string{"exists"}, rows: [][]driver.Value{{tt.shared}}})
+
+			err := s.checkAssigneeTx(t.Context(), beginTx(t, s), 1, tt.taskID, &assigneeID)
+			if tt.wantErr {
+				if !errors.Is(err, models.ErrInvalidInput) {
+					t.Fatalf("err = %v, want ErrInvalidInput", err)
+				}
+			} else if err != nil {
+				t.Fatalf("checkAssigneeTx: %v", err)
+			}
+			if got := fake.called("SELECT role")[0].args[1]; got != assigneeID.String() {
+				t.Errorf("looked up the role of %v, want the assignee", got)
+			}
+			if tt.taskID == 0 && len(fake.called("task_shares")) != 0 {
+				t.Error("looked up shares of a task not created yet")
+			}
+		})
+	}
+}
+
+func TestCheckAssigneeUnassign(t *testing.T) {
+	s, fake := newTestService(t)
+	if err := s.checkAssigneeTx(t.Context(), beginTx(t, s), 1, 7, nil); err != nil {
+		t.Fatalf("checkAssigneeTx: %v", err)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("ran %d statements to unassign a task", len(fake.calls))
+	}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+)
+
+// applyTaskStatus resolves and validates the status and priority of a task being created
+// (prev is nil) or updated. Without an explicit status the legacy Completed flag decides:
+// it maps to done, and clearing it on a done task reopens it as todo. An empty priority
+// keeps the current one, or none for a new task. Completed is derived from the result.
+func applyTaskStatus(prev, task *models.Task) error {
+	if task.Status == "" {
+		switch {
+		case task.Completed:
+			task.Status = models.TaskStatusDone
+		case prev != nil && prev.Status != models.TaskStatusDone:
+			task.Status = prev.Status
+		default:
+			task.Status = models.TaskStatusTodo
+		}
+	}
+	if !models.IsValidTaskStatus(task.Status) {
+		return fmt.Errorf("%w: unknown status %q", models.ErrInvalidInput, task.Status)
+	}
+	if prev != nil && !models.CanTransitionTaskStatus(prev.Status, task.Status) {
+		return fmt.Errorf("%w: cannot move task from %s to %s", models.ErrInvalidTransition, prev.Status, task.Status)
+	}
+
+	if task.Priority == "" {
+		task.Priority = models.TaskPriorityNone
+		if prev != nil {
+			task.Priority = prev.Priority
+		}
+	}
+	if !models.IsValidTaskPriority(task.Priority) {
+		return fmt.Errorf("%w: unknown priority %q", models.ErrInvalidInput, task.Priority)
+	}
+
+	task.Completed = task.Status == models.TaskStatusDone
+	return nil
+}
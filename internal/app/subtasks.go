@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// ListSubtasks returns the direct subtasks of a task.
+// It returns sql.ErrNoRows when the parent task does not exist.
+func (s *TaskManagerService) ListSubtasks(ctx context.Context, id int32, userID uuid.UUID) ([]*models.Task, error) {
+	s.Log.Debug("Starting ListSubtasks", slog.Int("taskID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	parent, err := s.db.GetTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if parent == nil {
+		err = fmt.Errorf("task with id %d not found: %w", id, sql.ErrNoRows)
+		return nil, err
+	}
+
+	tasks, err := s.db.ListTaskChildrenTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.loadTaskDetailsTx(ctx, tx, tasks...); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Subtasks listed successfully", slog.Int("count", len(tasks)))
+	return tasks, nil
+}
+
+// GetTaskTree returns a task with all of its descendants nested in Children.
+// It returns nil when the task does not exist.
+func (s *TaskManagerService) GetTaskTree(ctx context.Context, id int32, userID uuid.UUID) (*models.Task, error) {
+	s.Log.Debug("Starting GetTaskTree", slog.Int("taskID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	root, all, err := s.db.GetTaskTreeTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.loadTaskDetailsTx(ctx, tx, all...); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return root, nil
+}
+
+// SetTaskParent nests a task under another task, or moves it to the top level when parentID is nil.
+func (s *TaskManagerService) SetTaskParent(ctx context.Context, id int32, parentID *int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting SetTaskParent", slog.Int("taskID", int(id)))
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if parentID != nil {
+		if err = s.checkSubtaskDepthTx(ctx, tx, &id, *parentID, userID); err != nil {
+			return err
+		}
+	}
+
+	if err = s.db.SetTaskParentTx(ctx, tx, id, parentID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.taskNotEditableTx(ctx, tx, id, userID)
+		}
+		return fmt.Errorf("failed to set task parent: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Task parent changed successfully", slog.Int("taskID", int(id)))
+	return nil
+}
+
+// checkSubtaskDepthTx rejects nesting that would exceed the configured depth limit.
+// taskID is nil for a task that is about to be created.
+func (s *TaskManagerService) checkSubtaskDepthTx(ctx context.Context, tx *sql.Tx, taskID *int32, parentID int32, userID uuid.UUID) error {
+	maxDepth := s.cfg.MaxSubtaskDepth
+	if maxDepth <= 0 {
+		return nil
+	}
+
+	ancestors, err := s.db.GetTaskAncestorsTx(ctx, tx, parentID, userID)
+	if err != nil {
+		return err
+	}
+	if len(ancestors) == 0 {
+		return fmt.Errorf("%w: parent task %d does not exist", models.ErrInvalidInput, parentID)
+	}
+
+	height := 1
+	if taskID != nil {
+		if height, err = s.db.GetTaskSubtreeHeightTx(ctx, tx, *taskID, userID); err != nil {
+			return err
+		}
+	}
+
+	if len(ancestors)+height > maxDepth {
+		return fmt.Errorf("%w: subtasks can be nested at most %d levels deep", models.ErrInvalidInput, maxDepth)
+	}
+	return nil
+}
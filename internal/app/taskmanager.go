@@ -6,34 +6,52 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/HellUpa/taskmanager/internal/config"
 	"github.com/HellUpa/taskmanager/internal/db"
 	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
 	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/HellUpa/taskmanager/internal/storage"
 	"github.com/google/uuid"
 )
 
 type TaskManagerService struct {
-	db  *db.PostgresDB
-	Log *slog.Logger
+	db          *db.PostgresDB
+	cfg         config.TasksConfig
+	blobs       storage.BlobStore
+	attachments config.AttachmentsConfig
+	Log         *slog.Logger
 }
 
-func NewTaskManagerService(log *slog.Logger, db *db.PostgresDB) *TaskManagerService {
+func NewTaskManagerService(log *slog.Logger, db *db.PostgresDB, cfg config.TasksConfig, blobs storage.BlobStore, attachments config.AttachmentsConfig) *TaskManagerService {
 	log.Debug("Initializing TaskManagerService")
 	return &TaskManagerService{
-		db:  db,
-		Log: log,
+		db:          db,
+		cfg:         cfg,
+		blobs:       blobs,
+		attachments: attachments,
+		Log:         log,
 	}
 }
 
-// CreateTask creates a new task.
+// CreateTask creates a new task. The task goes to the workspace of its parent or project,
+// or to the requested workspace, and otherwise to the default project of the user.
+// The user must be allowed to edit the workspace. On success task is replaced by the stored task.
 func (s *TaskManagerService) CreateTask(ctx context.Context, task *models.Task, userID uuid.UUID) (int32, error) {
 	s.Log.Debug("Starting CreateTask", slog.String("userID", userID.String()))
 	task.UserID = userID
+	if err := applyTaskStatus(nil, task); err != nil {
+		return 0, err
+	}
+	if err := task.NormalizeDueDate(); err != nil {
+		return 0, err
+	}
 
-	tx, err := s.db.DB.BeginTx(ctx, nil)
+	tx, err := s.beginAuditedTx(ctx, userID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, err
 	}
 
 	defer func() {
@@ -44,10 +62,85 @@ func (s *TaskManagerService) CreateTask(ctx context.Context, task *models.Task,
 		}
 	}()
 
+	if task.WorkspaceID != 0 {
+		if _, err = s.editableWorkspaceTx(ctx, tx, task.WorkspaceID, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	if task.ParentID != nil {
+		var parent *models.Task
+		if parent, err = s.db.GetTaskTx(ctx, tx, *task.ParentID, userID); err != nil {
+			return 0, err
+		}
+		if parent == nil {
+			err = fmt.Errorf("%w: parent task %d does not exist", models.ErrInvalidInput, *task.ParentID)
+			return 0, err
+		}
+		if err = s.checkSubtaskDepthTx(ctx, tx, nil, *task.ParentID, userID); err != nil {
+			return 0, err
+		}
+		if task.WorkspaceID != 0 && task.WorkspaceID != parent.WorkspaceID {
+			err = fmt.Errorf("%w: parent task %d is in another workspace", models.ErrInvalidInput, *task.ParentID)
+			return 0, err
+		}
+		task.WorkspaceID = parent.WorkspaceID
+		// Subtasks live in their parent's project unless told otherwise.
+		if task.ProjectID == nil {
+			task.ProjectID = parent.ProjectID
+		}
+	}
+
+	if task.ProjectID == nil {
+		var project *models.Project
+		if project, err = s.defaultProjectTx(ctx, tx, userID, task.WorkspaceID); err != nil {
+			return 0, err
+		}
+		task.ProjectID, task.WorkspaceID = &project.ID, project.WorkspaceID
+	} else {
+		var project *models.Project
+		if project, err = s.db.GetProjectTx(ctx, tx, *task.ProjectID, userID); err != nil {
+			return 0, fmt.Errorf("failed to get project: %w", err)
+		}
+		if project == nil {
+			err = fmt.Errorf("%w: project %d does not exist", models.ErrInvalidInput, *task.ProjectID)
+			return 0, err
+		}
+		if task.WorkspaceID == 0 {
+			task.WorkspaceID = project.WorkspaceID
+		}
+		if err = s.checkTaskProjectTx(ctx, tx, *task.ProjectID, task.WorkspaceID, userID); err != nil {
+			return 0, err
+		}
+	}
+	if _, err = s.requireWorkspaceRoleTx(ctx, tx, task.WorkspaceID, userID, models.CanEditWorkspaceContent); err != nil {
+		return 0, err
+	}
+	if err = s.checkAssigneeTx(ctx, tx, task.WorkspaceID, 0, task.AssigneeID); err != nil {
+		return 0, err
+	}
+
+	if err = s.startSeriesTx(ctx, tx, task); err != nil {
+		return 0, err
+	}
+
 	id, err := s.db.CreateTaskTx(ctx, tx, task)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create task: %w", err)
 	}
+	task.ID = id
+
+	if task.LabelIDs != nil {
+		if err = s.db.SetTaskLabelsTx(ctx, tx, id, userID, task.LabelIDs); err != nil {
+			return 0, fmt.Errorf("failed to set task labels: %w", err)
+		}
+	}
+	// Read the task back, attaching labels bumped the version CreateTaskTx returned.
+	stored, err := s.reloadTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return 0, err
+	}
+	*task = *stored
 
 	if err := tx.Commit(); err != nil {
 		return 0, fmt.Errorf("failed to commit transaction: %w", err)
@@ -56,7 +149,8 @@ func (s *TaskManagerService) CreateTask(ctx context.Context, task *models.Task,
 	return id, nil
 }
 
-// GetTask retrieves a task by its ID.
+// GetTask retrieves a task by its ID, if the user may view it through its workspace,
+// as its assignee or through a share.
 func (s *TaskManagerService) GetTask(ctx context.Context, id int32, userID uuid.UUID) (*models.Task, error) {
 	s.Log.Debug("Starting GetTask", slog.Int("taskID", int(id)), slog.String("userID", userID.String()))
 	tx, err := s.db.DB.BeginTx(ctx, nil)
@@ -75,6 +169,11 @@ func (s *TaskManagerService) GetTask(ctx context.Context, id int32, userID uuid.
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
+	if task != nil {
+		if err = s.loadTaskDetailsTx(ctx, tx, task); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -83,12 +182,12 @@ func (s *TaskManagerService) GetTask(ctx context.Context, id int32, userID uuid.
 	return task, nil
 }
 
-// UpdateTask updates a task.
-func (s *TaskManagerService) UpdateTask(ctx context.Context, task *models.Task) error {
+// UpdateTask replaces the editable fields of a task and returns the task as stored.
+func (s *TaskManagerService) UpdateTask(ctx context.Context, task *models.Task, opts models.TaskUpdateOptions) (*models.Task, error) {
 	s.Log.Debug("Starting UpdateTask", slog.Int("taskID", int(task.ID)))
-	tx, err := s.db.DB.BeginTx(ctx, nil)
+	tx, err := s.beginAuditedTx(ctx, task.UserID)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, err
 	}
 	defer func() {
 		if err != nil {
@@ -98,26 +197,142 @@ func (s *TaskManagerService) UpdateTask(ctx context.Context, task *models.Task)
 		}
 	}()
 
-	if err := s.db.UpdateTaskTx(ctx, tx, task); err != nil {
+	prev, err := s.db.GetTaskTx(ctx, tx, task.ID, task.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if prev == nil {
+		err = fmt.Errorf("task with id %d not found: %w", task.ID, sql.ErrNoRows)
+		return nil, err
+	}
+
+	if err = s.updateTaskTx(ctx, tx, prev, task, opts); err != nil {
+		return nil, err
+	}
+	updated, err := s.reloadTaskTx(ctx, tx, task.ID, task.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Task updated successfully", slog.Int("taskID", int(task.ID)))
+	return updated, nil
+}
+
+// updateTaskTx writes task over its previous state prev and applies the side effects of the change:
+// status workflow, project and nesting checks, series updates, activity events, subtask
+// completion, scheduling of the next occurrence and label replacement. task.UserID is the user making
+// the change, who must be allowed to edit the task. Users editing the task without editing its
+// workspace are limited by checkTaskOnlyUpdate.
+func (s *TaskManagerService) updateTaskTx(ctx context.Context, tx *sql.Tx, prev, task *models.Task, opts models.TaskUpdateOptions) error {
+	task.WorkspaceID = prev.WorkspaceID
+	if err := s.requireTaskAccessTx(ctx, tx, prev.ID, task.UserID, models.TaskAccessEdit); err != nil {
+		return err
+	}
+	role, err := s.db.GetWorkspaceRoleTx(ctx, tx, prev.WorkspaceID, task.UserID, false)
+	if err != nil {
+		return err
+	}
+	if !models.CanEditWorkspaceContent(role) {
+		if err := s.checkTaskOnlyUpdate(ctx, tx, prev, task, opts); err != nil {
+			return err
+		}
+	}
+	// Fail early on a stale version; UpdateTaskTx repeats the check atomically.
+	if opts.IfMatch != nil && !slices.Contains(opts.IfMatch, prev.Version) {
+		return models.ErrPreconditionFailed
+	}
+	if err := applyTaskStatus(prev, task); err != nil {
+		return err
+	}
+	if err := task.NormalizeDueDate(); err != nil {
+		return err
+	}
+
+	if task.ProjectID != nil {
+		if err := s.checkTaskProjectTx(ctx, tx, *task.ProjectID, task.WorkspaceID, task.UserID); err != nil {
+			return err
+		}
+	}
+
+	if task.ParentID != nil {
+		if err := s.checkSubtaskDepthTx(ctx, tx, &task.ID, *task.ParentID, task.UserID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.updateSeriesTx(ctx, tx, prev, task, opts.Scope); err != nil {
+		return err
+	}
+
+	if err := s.db.UpdateTaskTx(ctx, tx, task, opts.IfMatch); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("task with id %d not found: %w", task.ID, err)
 		}
-		return fmt.Errorf("failed to update task: %v", err)
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+	if err := s.recordTaskActivityTx(ctx, tx, prev, task); err != nil {
+		return err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if opts.CompleteSubtasks && task.Completed {
+		if err := s.db.CompleteDescendantsTx(ctx, tx, task.ID, task.UserID); err != nil {
+			return err
+		}
+	}
+
+	if task.Completed && !prev.Completed {
+		if err := s.scheduleNextOccurrenceTx(ctx, tx, task); err != nil {
+			return err
+		}
+	}
+
+	if task.LabelIDs != nil {
+		if err := s.db.SetTaskLabelsTx(ctx, tx, task.ID, task.UserID, task.LabelIDs); err != nil {
+			return fmt.Errorf("failed to set task labels: %w", err)
+		}
 	}
-	s.Log.Debug("Task updated successfully", slog.Int("taskID", int(task.ID)))
 	return nil
 }
 
-// DeleteTask deletes a task by its ID.
-func (s *TaskManagerService) DeleteTask(ctx context.Context, id int32, userID uuid.UUID) error {
+// taskNotEditableTx explains why a write to a task matched no row: the task is not visible
+// to the user, or the user only views its workspace.
+func (s *TaskManagerService) taskNotEditableTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) error {
+	task, err := s.db.GetTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if task == nil {
+		return fmt.Errorf("task with id %d not found: %w", id, sql.ErrNoRows)
+	}
+	return fmt.Errorf("task %d: %w", id, models.ErrForbidden)
+}
+
+// reloadTaskTx reads a task back from the database together with its details.
+func (s *TaskManagerService) reloadTaskTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Task, error) {
+	task, err := s.db.GetTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task with id %d not found: %w", id, sql.ErrNoRows)
+	}
+	if err := s.loadTaskDetailsTx(ctx, tx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// DeleteTask moves a task with its subtasks to the trash, from where RestoreTask brings it back until
+// the retention window ends. Only owners of the task may delete it: its creator while editing its
+// workspace and the owners of the workspace. A non-nil ifMatch only deletes the task while it has one of the given versions.
+func (s *TaskManagerService) DeleteTask(ctx context.Context, id int32, userID uuid.UUID, ifMatch []int32) error {
 	s.Log.Debug("Starting DeleteTask", slog.Int("taskID", int(id)), slog.String("userID", userID.String()))
-	tx, err := s.db.DB.BeginTx(ctx, nil)
+	tx, err := s.beginAuditedTx(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return err
 	}
 	defer func() {
 		if err != nil {
@@ -127,11 +342,11 @@ func (s *TaskManagerService) DeleteTask(ctx context.Context, id int32, userID uu
 		}
 	}()
 
-	if err := s.db.DeleteTaskTx(ctx, tx, id, userID); err != nil {
+	if err = s.db.DeleteTaskTx(ctx, tx, id, userID, ifMatch); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("task with id %d not found: %w", id, err)
 		}
-		return fmt.Errorf("failed to delete task: %v", err)
+		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -141,8 +356,13 @@ func (s *TaskManagerService) DeleteTask(ctx context.Context, id int32, userID uu
 	return nil
 }
 
-func (s *TaskManagerService) ListTasks(ctx context.Context, userID uuid.UUID) ([]*models.Task, error) {
+// ListTasks returns a page of the tasks in the user's workspaces matching the given options.
+func (s *TaskManagerService) ListTasks(ctx context.Context, userID uuid.UUID, opts *models.TaskListOptions) (*models.TaskPage, error) {
 	s.Log.Debug("Starting ListTasks", slog.String("userID", userID.String()))
+	if err := opts.Normalize(); err != nil {
+		return nil, err
+	}
+
 	tx, err := s.db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
@@ -155,19 +375,88 @@ func (s *TaskManagerService) ListTasks(ctx context.Context, userID uuid.UUID) ([
 		}
 	}()
 
-	tasks, err := s.db.ListTasksTx(ctx, tx, userID)
+	// Due filters are evaluated in the user's time zone unless the request chose one.
+	if opts.Due != "" && opts.Location == nil {
+		if opts.Location, err = s.userLocationTx(ctx, tx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	tasks, hasMore, err := s.db.ListTasksTx(ctx, tx, userID, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tasks: %v", err)
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if err = s.loadTaskDetailsTx(ctx, tx, tasks...); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	s.Log.Debug("Tasks listed successfully")
-	return tasks, nil
+
+	page := &models.TaskPage{Tasks: tasks}
+	if hasMore && len(tasks) > 0 {
+		page.NextCursor = opts.CursorAfter(tasks[len(tasks)-1]).Encode()
+	}
+	s.Log.Debug("Tasks listed successfully", slog.Int("count", len(tasks)))
+	return page, nil
 }
 
-// CreateUser creates a new task.
+// SearchTasks performs a full-text search over the tasks in the user's workspaces.
+func (s *TaskManagerService) SearchTasks(ctx context.Context, userID uuid.UUID, query string, limit int) ([]*models.TaskSearchResult, error) {
+	s.Log.Debug("Starting SearchTasks", slog.String("userID", userID.String()), slog.String("query", query))
+	if limit <= 0 {
+		limit = models.DefaultTaskListLimit
+	}
+	if limit > models.MaxTaskListLimit {
+		limit = models.MaxTaskListLimit
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	results, err := s.db.SearchTasksTx(ctx, tx, userID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tasks: %w", err)
+	}
+	tasks := make([]*models.Task, 0, len(results))
+	for _, result := range results {
+		tasks = append(tasks, result.Task)
+	}
+	if err = s.loadTaskDetailsTx(ctx, tx, tasks...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Tasks searched successfully", slog.Int("count", len(results)))
+	return results, nil
+}
+
+// loadTaskDetailsTx fills the related data shown with every task:
+// labels, subtask progress and the recurrence rule.
+func (s *TaskManagerService) loadTaskDetailsTx(ctx context.Context, tx *sql.Tx, tasks ...*models.Task) error {
+	if err := s.db.LoadTaskLabelsTx(ctx, tx, tasks...); err != nil {
+		return err
+	}
+	if err := s.db.LoadSubtaskProgressTx(ctx, tx, tasks...); err != nil {
+		return err
+	}
+	return s.db.LoadTaskRecurrenceTx(ctx, tx, tasks...)
+}
+
+// CreateUser creates a new user together with their personal workspace, its Inbox project and default settings.
+// When a user with the same Kratos ID exists, it is loaded into user instead.
 func (s *TaskManagerService) CreateUser(ctx context.Context, user *models.User) error {
 	s.Log.Debug("Starting CreateUser", slog.Any("user", user))
 	tx, err := s.db.DB.BeginTx(ctx, nil)
@@ -183,17 +472,65 @@ func (s *TaskManagerService) CreateUser(ctx context.Context, user *models.User)
 		}
 	}()
 
-	if err := s.db.CreateUserTx(ctx, tx, user); err != nil {
+	if user.DisplayName == "" {
+		user.DisplayName = models.DefaultDisplayName(user.Email)
+	}
+	created, err := s.db.CreateUserTx(ctx, tx, user)
+	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
+	if !created {
+		// Kratos retries webhooks, creating a user that already exists returns the stored one.
+		var existing *models.User
+		if existing, err = s.db.GetUserByKratosIDTx(ctx, tx, user.KratosID); err != nil {
+			return err
+		}
+		if existing == nil {
+			err = fmt.Errorf("user with Kratos ID %s vanished during creation", user.KratosID)
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		*user = *existing
+		s.Log.Debug("User already exists", slog.String("userID", user.ID.String()))
+		return nil
+	}
 
-	if err := tx.Commit(); err != nil {
+	// Every user starts with a personal workspace and an Inbox project that collects tasks without an explicit project.
+	var personal *models.Workspace
+	if personal, err = s.ensurePersonalWorkspaceTx(ctx, tx, user.ID); err != nil {
+		return err
+	}
+	if _, err = s.ensureInboxTx(ctx, tx, personal.ID, user.ID); err != nil {
+		return err
+	}
+
+	settings := models.DefaultUserSettings()
+	settings.TimeZone = user.TimeZone
+	if err = s.db.SaveUserSettingsTx(ctx, tx, user.ID, settings); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	s.Log.Debug("User created successfully", slog.String("userID", user.ID.String()))
 	return nil
 }
 
+// EnsureUser returns the user with the given Kratos ID, creating it from the identity traits
+// when it does not exist yet. It reports whether the user was created.
+func (s *TaskManagerService) EnsureUser(ctx context.Context, kratosID string, traits models.IdentityTraits) (*models.User, bool, error) {
+	id := uuid.New()
+	user := &models.User{ID: id, KratosID: kratosID, Email: traits.Email}
+	// CreateUser loads the existing user, with its own ID, on a conflict.
+	if err := s.CreateUser(ctx, user); err != nil {
+		return nil, false, err
+	}
+	return user, user.ID == id, nil
+}
+
 // GetUserByKratosID retrieves a user by their Kratos ID.
 func (s *TaskManagerService) GetUserByKratosID(ctx context.Context, kratosID string) (*models.User, error) {
 	s.Log.Debug("Starting GetUserByKratosID", slog.String("kratosID", kratosID))
@@ -222,6 +559,23 @@ func (s *TaskManagerService) GetUserByKratosID(ctx context.Context, kratosID str
 	return user, nil
 }
 
+// userLocationTx returns the time zone of a user, falling back to UTC for unknown zones.
+func (s *TaskManagerService) userLocationTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (*time.Location, error) {
+	user, err := s.db.GetUserByIDTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return time.UTC, nil
+	}
+	loc, err := models.LoadTimeZone(user.TimeZone)
+	if err != nil {
+		s.Log.Warn("Invalid stored time zone, using UTC", slog.String("userID", userID.String()), slog.String("timeZone", user.TimeZone))
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
 // GetUserByID retrieves a user by their  ID.
 func (s *TaskManagerService) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	s.Log.Debug("Starting GetUserByID", slog.String("userID", id.String()))
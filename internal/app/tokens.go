@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// CreateToken issues a new personal access token. The returned token carries the plain
+// token in its Token field; it is not stored and cannot be retrieved again.
+func (s *TaskManagerService) CreateToken(ctx context.Context, token *models.PersonalAccessToken, userID uuid.UUID) error {
+	s.Log.Debug("Starting CreateToken", slog.String("userID", userID.String()))
+	if err := token.Normalize(); err != nil {
+		return err
+	}
+	token.UserID = userID
+
+	secret, prefix, err := models.NewPersonalAccessTokenSecret()
+	if err != nil {
+		return err
+	}
+	token.Prefix = prefix
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.CreateTokenTx(ctx, tx, token, models.HashToken(secret)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	token.Token = secret
+	s.Log.Debug("Token created successfully", slog.Int("tokenID", int(token.ID)))
+	return nil
+}
+
+// ListTokens returns all personal access tokens of a user, including revoked and expired ones.
+func (s *TaskManagerService) ListTokens(ctx context.Context, userID uuid.UUID) ([]*models.PersonalAccessToken, error) {
+	s.Log.Debug("Starting ListTokens", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	tokens, err := s.db.ListTokensTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken revokes a personal access token of a user.
+func (s *TaskManagerService) RevokeToken(ctx context.Context, id int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting RevokeToken", slog.Int("tokenID", int(id)))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.RevokeTokenTx(ctx, tx, id, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("active token with id %d not found: %w", id, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Token revoked successfully", slog.Int("tokenID", int(id)))
+	return nil
+}
+
+// AuthenticateToken resolves a plain personal access token and records its use.
+// It returns nil for unknown, revoked or expired tokens.
+func (s *TaskManagerService) AuthenticateToken(ctx context.Context, secret string) (*models.PersonalAccessToken, error) {
+	if !strings.HasPrefix(secret, models.PersonalAccessTokenPrefix) {
+		return nil, nil
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	token, err := s.db.GetActiveTokenByHashTx(ctx, tx, models.HashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token != nil {
+		if err = s.db.TouchTokenTx(ctx, tx, token.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// ListTrash retrieves the deleted tasks the user may restore, most recently deleted first.
+func (s *TaskManagerService) ListTrash(ctx context.Context, userID uuid.UUID) ([]*models.Task, error) {
+	s.Log.Debug("Starting ListTrash", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	tasks, err := s.db.ListTrashTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.loadTaskDetailsTx(ctx, tx, tasks...); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return tasks, nil
+}
+
+// RestoreTask takes a task out of the trash, together with the subtasks deleted along with it,
+// and returns it. Only owners of the task may restore it. A subtask whose parent is still
+// in the trash cannot be restored on its own.
+func (s *TaskManagerService) RestoreTask(ctx context.Context, id int32, userID uuid.UUID) (*models.Task, error) {
+	s.Log.Debug("Starting RestoreTask", slog.Int("taskID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	task, err := s.db.GetTrashedTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		err = fmt.Errorf("task with id %d not found in the trash: %w", id, sql.ErrNoRows)
+		return nil, err
+	}
+	if task.ParentID != nil {
+		var parentTrashed bool
+		if parentTrashed, err = s.db.TaskInTrashTx(ctx, tx, *task.ParentID); err != nil {
+			return nil, err
+		}
+		if parentTrashed {
+			err = fmt.Errorf("%w: parent task %d is in the trash, restore it first", models.ErrInvalidInput, *task.ParentID)
+			return nil, err
+		}
+	}
+
+	if err = s.db.RestoreTaskTx(ctx, tx, id, *task.DeletedAt); err != nil {
+		return nil, err
+	}
+	restored, err := s.reloadTaskTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Task restored successfully", slog.Int("taskID", int(id)))
+	return restored, nil
+}
+
+// PurgeTask permanently deletes a task in the trash with its subtasks, comments and attachments.
+// Only owners of the task may purge it.
+func (s *TaskManagerService) PurgeTask(ctx context.Context, id int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting PurgeTask", slog.Int("taskID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.beginAuditedTx(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.PurgeTaskTx(ctx, tx, id, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("task with id %d not found in the trash: %w", id, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// The attachments of the task and its subtasks went with it, their blobs are queued for deletion.
+	if _, err := s.PurgeDeletedBlobs(ctx); err != nil {
+		s.Log.Warn("Failed to delete attachment blobs, leaving them to the cleaner", logu.Err(err))
+	}
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes the tasks that have been in the trash for longer than
+// the configured retention and returns their number.
+func (s *TaskManagerService) PurgeExpiredTrash(ctx context.Context) (int64, error) {
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	purged, err := s.db.PurgeDeletedTasksTx(ctx, tx, time.Now().Add(-s.cfg.TrashRetention))
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return purged, nil
+}
+
+// RunTrashPurger purges expired tasks from the trash every configured interval until ctx is done.
+func (s *TaskManagerService) RunTrashPurger(ctx context.Context) {
+	interval := s.cfg.TrashPurgeInterval
+	if interval <= 0 {
+		s.Log.Info("Trash purger disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpiredTrash(ctx)
+			if err != nil {
+				s.Log.Error("Failed to purge expired trash", logu.Err(err))
+				continue
+			}
+			if purged > 0 {
+				s.Log.Info("Expired tasks purged from the trash", slog.Int64("count", purged))
+			}
+		}
+	}
+}
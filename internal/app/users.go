@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// UpdateProfile changes the display name of a user and returns the updated profile.
+func (s *TaskManagerService) UpdateProfile(ctx context.Context, userID uuid.UUID, displayName string) (*models.User, error) {
+	s.Log.Debug("Starting UpdateProfile", slog.String("userID", userID.String()))
+	displayName, err := models.NormalizeDisplayName(displayName)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	user := &models.User{ID: userID, DisplayName: displayName}
+	if err = s.db.UpdateUserProfileTx(ctx, tx, user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user %s not found: %w", userID, err)
+		}
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Profile updated successfully", slog.String("userID", userID.String()))
+	return user, nil
+}
+
+// GetSettings returns the settings of a user, or the defaults when none are stored yet.
+func (s *TaskManagerService) GetSettings(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	s.Log.Debug("Starting GetSettings", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	settings, err := s.db.GetUserSettingsTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		var user *models.User
+		if user, err = s.db.GetUserByIDTx(ctx, tx, userID); err != nil {
+			return nil, err
+		}
+		settings = models.DefaultUserSettings()
+		if user != nil {
+			settings.TimeZone = user.TimeZone
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return settings, nil
+}
+
+// UpdateSettings replaces the settings of a user. The default project must be an active project of the user.
+func (s *TaskManagerService) UpdateSettings(ctx context.Context, userID uuid.UUID, settings *models.UserSettings) error {
+	s.Log.Debug("Starting UpdateSettings", slog.String("userID", userID.String()))
+	if err := settings.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if settings.DefaultProjectID != nil {
+		// The default project may be in any workspace the user can add tasks to.
+		var project *models.Project
+		if project, err = s.db.GetProjectTx(ctx, tx, *settings.DefaultProjectID, userID); err != nil {
+			return fmt.Errorf("failed to get project: %w", err)
+		}
+		if project == nil {
+			err = fmt.Errorf("%w: project %d does not exist", models.ErrInvalidInput, *settings.DefaultProjectID)
+			return err
+		}
+		if err = s.checkTaskProjectTx(ctx, tx, project.ID, project.WorkspaceID, userID); err != nil {
+			return err
+		}
+		if _, err = s.requireWorkspaceRoleTx(ctx, tx, project.WorkspaceID, userID, models.CanEditWorkspaceContent); err != nil {
+			return err
+		}
+	}
+
+	if err = s.db.SaveUserSettingsTx(ctx, tx, userID, settings); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user %s not found: %w", userID, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Settings updated successfully", slog.String("userID", userID.String()))
+	return nil
+}
+
+// SyncUserTraits copies changed Kratos identity traits onto the user with the given Kratos ID.
+func (s *TaskManagerService) SyncUserTraits(ctx context.Context, kratosID string, traits models.IdentityTraits) error {
+	s.Log.Debug("Starting SyncUserTraits", slog.String("kratosID", kratosID))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.UpdateUserTraitsTx(ctx, tx, kratosID, traits); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user with Kratos ID %s not found: %w", kratosID, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("User traits synced successfully", slog.String("kratosID", kratosID))
+	return nil
+}
@@ -0,0 +1,581 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	logu "github.com/HellUpa/taskmanager/internal/logger/logger-utils"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// CreateWorkspace creates a shared workspace with the user as its owner, together with its Inbox project.
+func (s *TaskManagerService) CreateWorkspace(ctx context.Context, workspace *models.Workspace, userID uuid.UUID) error {
+	s.Log.Debug("Starting CreateWorkspace", slog.String("userID", userID.String()))
+	workspace.IsPersonal = false
+	workspace.CreatedBy = &userID
+	if err := workspace.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if err = s.db.CreateWorkspaceTx(ctx, tx, workspace); err != nil {
+		return err
+	}
+	if _, err = s.db.AddWorkspaceMemberTx(ctx, tx, workspace.ID, userID, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+	workspace.Role = models.WorkspaceRoleOwner
+	if _, err = s.ensureInboxTx(ctx, tx, workspace.ID, userID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Workspace created successfully", slog.Int("workspaceID", int(workspace.ID)))
+	return nil
+}
+
+// ListWorkspaces returns the workspaces the user is a member of.
+func (s *TaskManagerService) ListWorkspaces(ctx context.Context, userID uuid.UUID) ([]*models.Workspace, error) {
+	s.Log.Debug("Starting ListWorkspaces", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	workspaces, err := s.db.ListWorkspacesTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return workspaces, nil
+}
+
+// GetWorkspace retrieves a workspace of the user. It returns nil when the user is not a member.
+func (s *TaskManagerService) GetWorkspace(ctx context.Context, id int32, userID uuid.UUID) (*models.Workspace, error) {
+	s.Log.Debug("Starting GetWorkspace", slog.Int("workspaceID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	workspace, err := s.db.GetWorkspaceTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return workspace, nil
+}
+
+// UpdateWorkspace renames a workspace. Only owners and admins may do so.
+func (s *TaskManagerService) UpdateWorkspace(ctx context.Context, workspace *models.Workspace, userID uuid.UUID) error {
+	s.Log.Debug("Starting UpdateWorkspace", slog.Int("workspaceID", int(workspace.ID)))
+	if err := workspace.Normalize(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if workspace.Role, err = s.requireWorkspaceRoleTx(ctx, tx, workspace.ID, userID, models.CanManageWorkspace); err != nil {
+		return err
+	}
+	if err = s.db.UpdateWorkspaceTx(ctx, tx, workspace); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("workspace with id %d not found: %w", workspace.ID, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Workspace updated successfully", slog.Int("workspaceID", int(workspace.ID)))
+	return nil
+}
+
+// DeleteWorkspace deletes a shared workspace with all of its content. Only owners may do so,
+// personal workspaces are deleted with their user.
+func (s *TaskManagerService) DeleteWorkspace(ctx context.Context, id int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting DeleteWorkspace", slog.Int("workspaceID", int(id)), slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	workspace, err := s.sharedWorkspaceTx(ctx, tx, id, userID, isWorkspaceOwner)
+	if err != nil {
+		return err
+	}
+	if err = s.db.DeleteWorkspaceTx(ctx, tx, workspace.ID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Info("Workspace deleted", slog.Int("workspaceID", int(id)), slog.String("userID", userID.String()))
+	return nil
+}
+
+// ListWorkspaceMembers returns the members of a workspace the user belongs to.
+func (s *TaskManagerService) ListWorkspaceMembers(ctx context.Context, id int32, userID uuid.UUID) ([]*models.WorkspaceMember, error) {
+	s.Log.Debug("Starting ListWorkspaceMembers", slog.Int("workspaceID", int(id)))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if _, err = s.requireWorkspaceRoleTx(ctx, tx, id, userID, models.IsValidWorkspaceRole); err != nil {
+		return nil, err
+	}
+	members, err := s.db.ListWorkspaceMembersTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return members, nil
+}
+
+// UpdateWorkspaceMemberRole changes the role of a member. Owners and admins manage roles,
+// but only owners may grant or take away ownership, and the last owner cannot be demoted.
+func (s *TaskManagerService) UpdateWorkspaceMemberRole(ctx context.Context, id int32, memberID uuid.UUID, role string, userID uuid.UUID) error {
+	s.Log.Debug("Starting UpdateWorkspaceMemberRole", slog.Int("workspaceID", int(id)), slog.String("memberID", memberID.String()), slog.String("role", role))
+	if !models.IsValidWorkspaceRole(role) {
+		return fmt.Errorf("%w: role must be one of owner, admin, member, viewer", models.ErrInvalidInput)
+	}
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	workspace, err := s.sharedWorkspaceTx(ctx, tx, id, userID, models.CanManageWorkspace)
+	if err != nil {
+		return err
+	}
+	current, err := s.memberRoleTx(ctx, tx, id, memberID)
+	if err != nil {
+		return err
+	}
+	if (current == models.WorkspaceRoleOwner || role == models.WorkspaceRoleOwner) && workspace.Role != models.WorkspaceRoleOwner {
+		err = fmt.Errorf("only owners may grant or revoke ownership: %w", models.ErrForbidden)
+		return err
+	}
+	if current == models.WorkspaceRoleOwner && role != models.WorkspaceRoleOwner {
+		if err = s.checkNotLastOwnerTx(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	if err = s.db.SetWorkspaceMemberRoleTx(ctx, tx, id, memberID, role); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Workspace member role updated", slog.Int("workspaceID", int(id)), slog.String("memberID", memberID.String()))
+	return nil
+}
+
+// RemoveWorkspaceMember removes a member from a workspace. Members may always leave,
+// removing others needs the owner or admin role and only owners may remove owners.
+// The last owner can neither leave nor be removed.
+func (s *TaskManagerService) RemoveWorkspaceMember(ctx context.Context, id int32, memberID uuid.UUID, userID uuid.UUID) error {
+	s.Log.Debug("Starting RemoveWorkspaceMember", slog.Int("workspaceID", int(id)), slog.String("memberID", memberID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	allowed := models.CanManageWorkspace
+	if memberID == userID {
+		allowed = models.IsValidWorkspaceRole
+	}
+	workspace, err := s.sharedWorkspaceTx(ctx, tx, id, userID, allowed)
+	if err != nil {
+		return err
+	}
+	current, err := s.memberRoleTx(ctx, tx, id, memberID)
+	if err != nil {
+		return err
+	}
+	if current == models.WorkspaceRoleOwner {
+		if memberID != userID && workspace.Role != models.WorkspaceRoleOwner {
+			err = fmt.Errorf("only owners may remove owners: %w", models.ErrForbidden)
+			return err
+		}
+		if err = s.checkNotLastOwnerTx(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	if err = s.db.RemoveWorkspaceMemberTx(ctx, tx, id, memberID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Workspace member removed", slog.Int("workspaceID", int(id)), slog.String("memberID", memberID.String()))
+	return nil
+}
+
+// InviteToWorkspace invites an email address to a shared workspace. Only owners and admins may invite.
+// Inviting an address with an open invitation renews it.
+func (s *TaskManagerService) InviteToWorkspace(ctx context.Context, inv *models.WorkspaceInvitation, userID uuid.UUID) error {
+	s.Log.Debug("Starting InviteToWorkspace", slog.Int("workspaceID", int(inv.WorkspaceID)))
+	if err := inv.Normalize(); err != nil {
+		return err
+	}
+	inv.InvitedBy = &userID
+	inv.ExpiresAt = time.Now().Add(models.WorkspaceInvitationTTL)
+	inv.AcceptedAt, inv.DeclinedAt = nil, nil
+
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	workspace, err := s.sharedWorkspaceTx(ctx, tx, inv.WorkspaceID, userID, models.CanManageWorkspace)
+	if err != nil {
+		return err
+	}
+	inv.WorkspaceName = workspace.Name
+
+	member, err := s.db.IsWorkspaceMemberEmailTx(ctx, tx, inv.WorkspaceID, inv.Email)
+	if err != nil {
+		return err
+	}
+	if member {
+		err = fmt.Errorf("member %s: %w", inv.Email, models.ErrAlreadyExists)
+		return err
+	}
+
+	if err = s.db.CreateWorkspaceInvitationTx(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Info("Workspace invitation created", slog.Int("workspaceID", int(inv.WorkspaceID)), slog.Int("invitationID", int(inv.ID)))
+	return nil
+}
+
+// ListWorkspaceInvitations returns the open invitations of a workspace. Only owners and admins may list them.
+func (s *TaskManagerService) ListWorkspaceInvitations(ctx context.Context, id int32, userID uuid.UUID) ([]*models.WorkspaceInvitation, error) {
+	s.Log.Debug("Starting ListWorkspaceInvitations", slog.Int("workspaceID", int(id)))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if _, err = s.requireWorkspaceRoleTx(ctx, tx, id, userID, models.CanManageWorkspace); err != nil {
+		return nil, err
+	}
+	invitations, err := s.db.ListWorkspaceInvitationsTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return invitations, nil
+}
+
+// RevokeWorkspaceInvitation deletes an open invitation. Only owners and admins may revoke invitations.
+func (s *TaskManagerService) RevokeWorkspaceInvitation(ctx context.Context, id, invitationID int32, userID uuid.UUID) error {
+	s.Log.Debug("Starting RevokeWorkspaceInvitation", slog.Int("workspaceID", int(id)), slog.Int("invitationID", int(invitationID)))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	if _, err = s.requireWorkspaceRoleTx(ctx, tx, id, userID, models.CanManageWorkspace); err != nil {
+		return err
+	}
+	if err = s.db.DeleteWorkspaceInvitationTx(ctx, tx, id, invitationID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("invitation with id %d not found: %w", invitationID, err)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Debug("Workspace invitation revoked", slog.Int("invitationID", int(invitationID)))
+	return nil
+}
+
+// ListMyInvitations returns the open invitations sent to the user's email address.
+func (s *TaskManagerService) ListMyInvitations(ctx context.Context, userID uuid.UUID) ([]*models.WorkspaceInvitation, error) {
+	s.Log.Debug("Starting ListMyInvitations", slog.String("userID", userID.String()))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	user, err := s.db.GetUserByIDTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		err = fmt.Errorf("user %s not found: %w", userID, sql.ErrNoRows)
+		return nil, err
+	}
+	invitations, err := s.db.ListInvitationsForEmailTx(ctx, tx, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return invitations, nil
+}
+
+// AnswerInvitation accepts or declines an invitation sent to the user's email address.
+// Accepting adds the user to the workspace with the invited role and returns the workspace,
+// a user who already is a member keeps their role.
+func (s *TaskManagerService) AnswerInvitation(ctx context.Context, invitationID int32, userID uuid.UUID, accept bool) (*models.Workspace, error) {
+	s.Log.Debug("Starting AnswerInvitation", slog.Int("invitationID", int(invitationID)), slog.Bool("accept", accept))
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.Log.Error("Rollback failed", logu.Err(rollbackErr))
+			}
+		}
+	}()
+
+	user, err := s.db.GetUserByIDTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		err = fmt.Errorf("user %s not found: %w", userID, sql.ErrNoRows)
+		return nil, err
+	}
+	// Invitations of other addresses are reported as missing, so their IDs cannot be probed.
+	inv, err := s.db.GetInvitationForEmailTx(ctx, tx, invitationID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil {
+		err = fmt.Errorf("invitation with id %d not found: %w", invitationID, sql.ErrNoRows)
+		return nil, err
+	}
+
+	if err = s.db.AnswerInvitationTx(ctx, tx, inv, accept); err != nil {
+		return nil, err
+	}
+	var workspace *models.Workspace
+	if accept {
+		if _, err = s.db.AddWorkspaceMemberTx(ctx, tx, inv.WorkspaceID, userID, inv.Role); err != nil {
+			return nil, err
+		}
+		if workspace, err = s.db.GetWorkspaceTx(ctx, tx, inv.WorkspaceID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.Log.Info("Workspace invitation answered", slog.Int("invitationID", int(invitationID)), slog.Bool("accepted", accept))
+	return workspace, nil
+}
+
+// requireWorkspaceRoleTx returns the user's role in a workspace. It fails with sql.ErrNoRows
+// when the user is not a member and with models.ErrForbidden when allowed rejects the role.
+func (s *TaskManagerService) requireWorkspaceRoleTx(ctx context.Context, tx *sql.Tx, workspaceID int32, userID uuid.UUID, allowed func(role string) bool) (string, error) {
+	role, err := s.db.GetWorkspaceRoleTx(ctx, tx, workspaceID, userID, false)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", fmt.Errorf("workspace with id %d not found: %w", workspaceID, sql.ErrNoRows)
+	}
+	if !allowed(role) {
+		return "", fmt.Errorf("role %s in workspace %d: %w", role, workspaceID, models.ErrForbidden)
+	}
+	return role, nil
+}
+
+// sharedWorkspaceTx returns a workspace the user has an allowed role in, rejecting personal
+// workspaces whose membership cannot change.
+func (s *TaskManagerService) sharedWorkspaceTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID, allowed func(role string) bool) (*models.Workspace, error) {
+	workspace, err := s.db.GetWorkspaceTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace == nil {
+		return nil, fmt.Errorf("workspace with id %d not found: %w", id, sql.ErrNoRows)
+	}
+	if !allowed(workspace.Role) {
+		return nil, fmt.Errorf("role %s in workspace %d: %w", workspace.Role, id, models.ErrForbidden)
+	}
+	if workspace.IsPersonal {
+		return nil, fmt.Errorf("%w: personal workspaces cannot be shared or deleted", models.ErrInvalidInput)
+	}
+	return workspace, nil
+}
+
+// memberRoleTx returns the role of a member and locks the membership, failing with sql.ErrNoRows for non-members.
+func (s *TaskManagerService) memberRoleTx(ctx context.Context, tx *sql.Tx, workspaceID int32, memberID uuid.UUID) (string, error) {
+	role, err := s.db.GetWorkspaceRoleTx(ctx, tx, workspaceID, memberID, true)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", fmt.Errorf("member %s not found: %w", memberID, sql.ErrNoRows)
+	}
+	return role, nil
+}
+
+// checkNotLastOwnerTx fails when the workspace has a single owner, who must stay.
+func (s *TaskManagerService) checkNotLastOwnerTx(ctx context.Context, tx *sql.Tx, workspaceID int32) error {
+	owners, err := s.db.CountWorkspaceOwnersTx(ctx, tx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return fmt.Errorf("%w: a workspace needs at least one owner", models.ErrInvalidInput)
+	}
+	return nil
+}
+
+// ensurePersonalWorkspaceTx returns the user's personal workspace, creating it if it is missing.
+func (s *TaskManagerService) ensurePersonalWorkspaceTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (*models.Workspace, error) {
+	workspace, err := s.db.GetPersonalWorkspaceTx(ctx, tx, userID)
+	if err != nil || workspace != nil {
+		return workspace, err
+	}
+
+	workspace = &models.Workspace{Name: models.PersonalWorkspaceName, IsPersonal: true, CreatedBy: &userID}
+	if err := s.db.CreateWorkspaceTx(ctx, tx, workspace); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.AddWorkspaceMemberTx(ctx, tx, workspace.ID, userID, models.WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+	workspace.Role = models.WorkspaceRoleOwner
+	return workspace, nil
+}
+
+// isWorkspaceOwner reports whether role is the owner role.
+func isWorkspaceOwner(role string) bool {
+	return role == models.WorkspaceRoleOwner
+}
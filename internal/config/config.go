@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -15,6 +16,8 @@ type Config struct {
 	HealthCheck HealthCheckConfig `yaml:"health_check"`
 	Telemetry   TelemetryConfig   `yaml:"telemetry"`
 	Auth        AuthConfig        `yaml:"auth"`
+	Tasks       TasksConfig       `yaml:"tasks"`
+	Attachments AttachmentsConfig `yaml:"attachments"`
 }
 type DatabaseConfig struct {
 	DBHost         string `yaml:"host"`
@@ -42,6 +45,69 @@ type TelemetryConfig struct {
 type AuthConfig struct {
 	KratosIP string `yaml:"kratos_ip"`
 	UI_IP    string `yaml:"ui_ip"`
+	// Session verifications are cached in-process, a size of 0 disables the cache. Logging out through
+	// POST /logout drops the cached session at once. Kratos sends no webhook on logout, so a session
+	// revoked in Kratos directly is still accepted until its cache entry expires, after SessionCacheTTL at most.
+	SessionCacheSize        int           `yaml:"session_cache_size" env-default:"10000"`
+	SessionCacheTTL         time.Duration `yaml:"session_cache_ttl" env-default:"1m"`
+	SessionCacheNegativeTTL time.Duration `yaml:"session_cache_negative_ttl" env-default:"10s"`
+	// OAuth2 access tokens are introspected with the Hydra admin API, an empty URL disables them.
+	HydraAdminURL        string        `yaml:"hydra_admin_url"`
+	IntrospectionTimeout time.Duration `yaml:"introspection_timeout" env-default:"5s"`
+	// Kratos webhooks must carry the API key or be signed with the secret, without either all are rejected.
+	WebhookAPIKey       string        `yaml:"webhook_api_key" env:"WEBHOOK_API_KEY"`
+	WebhookSecret       string        `yaml:"webhook_secret" env:"WEBHOOK_SECRET"`
+	WebhookReplayWindow time.Duration `yaml:"webhook_replay_window" env-default:"5m"`
+	// JITProvisioning creates users for valid sessions whose registration webhook was missed.
+	JITProvisioning bool `yaml:"jit_provisioning" env-default:"true"`
+	// DeleteKratosIdentity also deletes the Kratos identity, through the admin API, when a user deletes their account.
+	DeleteKratosIdentity bool `yaml:"delete_kratos_identity" env-default:"true"`
+}
+
+type TasksConfig struct {
+	MaxSubtaskDepth int `yaml:"max_subtask_depth" env-default:"5"`
+	// An idempotency key is leased for IdempotencyLease while its request runs, so that a crashed
+	// request frees it soon, and kept for IdempotencyTTL once the response is stored.
+	// The lease must outlast the request timeout.
+	IdempotencyLease         time.Duration `yaml:"idempotency_lease" env-default:"2m"`
+	IdempotencyTTL           time.Duration `yaml:"idempotency_ttl" env-default:"24h"`
+	IdempotencySweepInterval time.Duration `yaml:"idempotency_sweep_interval" env-default:"1h"`
+	// Deleted tasks stay in the trash for TrashRetention before the purger, running every
+	// TrashPurgeInterval, deletes them for good.
+	TrashRetention     time.Duration `yaml:"trash_retention" env-default:"720h"`
+	TrashPurgeInterval time.Duration `yaml:"trash_purge_interval" env-default:"1h"`
+}
+
+type AttachmentsConfig struct {
+	// Backend selects the blob store, local or s3.
+	Backend   string   `yaml:"backend" env-default:"local"`
+	LocalPath string   `yaml:"local_path" env-default:"./data/attachments"`
+	S3        S3Config `yaml:"s3"`
+	// StagingPath holds the chunks of resumable uploads until they complete.
+	StagingPath string `yaml:"staging_path" env-default:"./data/uploads"`
+	// MaxFileSize limits single files, UserQuota the total size of the files a user uploaded, in bytes.
+	MaxFileSize int64 `yaml:"max_file_size" env-default:"26214400"`
+	UserQuota   int64 `yaml:"user_quota" env-default:"1073741824"`
+	// AllowedTypes lists the accepted content types, as sniffed from the file contents.
+	AllowedTypes []string `yaml:"allowed_types" env-default:"image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain"`
+	// Unfinished resumable uploads expire after UploadTTL. The cleaner removes them and the blobs
+	// of deleted attachments every CleanupInterval.
+	UploadTTL       time.Duration `yaml:"upload_ttl" env-default:"24h"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"5m"`
+	// TransferTimeout replaces the server timeouts for uploads, upload chunks and downloads of attachment contents.
+	TransferTimeout time.Duration `yaml:"transfer_timeout" env-default:"10m"`
+}
+
+type S3Config struct {
+	// Endpoint is the base URL of the S3-compatible service, e.g. https://s3.eu-central-1.amazonaws.com or http://minio:9000.
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region" env-default:"us-east-1"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY"`
+	// PathStyle puts the bucket in the URL path instead of the host name, as MinIO expects.
+	PathStyle bool          `yaml:"path_style" env-default:"true"`
+	Timeout   time.Duration `yaml:"timeout" env-default:"5m"`
 }
 
 func MustLoad() *Config {
@@ -61,6 +127,11 @@ func MustLoad() *Config {
 		panic("config read goes wrong: " + err.Error())
 	}
 
+	// The example configs ship a placeholder that anyone could use to forge webhooks.
+	if strings.HasPrefix(cfg.Auth.WebhookAPIKey, "PLEASE-CHANGE-ME") {
+		panic("auth.webhook_api_key is the placeholder from the example config, set a random key")
+	}
+
 	return &cfg
 }
 
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// ListAllTasksTx retrieves every task of a user within a transaction, oldest first.
+func (pdb *PostgresDB) ListAllTasksTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]*models.Task, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE user_id = $1 ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	return collectTasks(rows)
+}
+
+// DeleteUserTx deletes a user within a transaction. All data of the user cascades.
+func (pdb *PostgresDB) DeleteUserTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteUserByKratosIDTx deletes the user of a Kratos identity within a transaction and returns its ID.
+// All data of the user cascades.
+func (pdb *PostgresDB) DeleteUserByKratosIDTx(ctx context.Context, tx *sql.Tx, kratosID string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, "DELETE FROM users WHERE kratos_id = $1 RETURNING id", kratosID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, sql.ErrNoRows
+		}
+		return uuid.Nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// attachmentColumns is the column list matching scanAttachment.
+const attachmentColumns = "id, task_id, user_id, filename, content_type, size, blob_key, created_at"
+
+// scanAttachment scans a row selected with attachmentColumns into an attachment.
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	a := &models.Attachment{}
+	if err := row.Scan(&a.ID, &a.TaskID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.BlobKey, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// uploadColumns is the column list matching scanUpload.
+const uploadColumns = "id, task_id, user_id, filename, size, received, created_at, expires_at"
+
+// scanUpload scans a row selected with uploadColumns into an upload.
+func scanUpload(row rowScanner) (*models.AttachmentUpload, error) {
+	u := &models.AttachmentUpload{}
+	if err := row.Scan(&u.ID, &u.TaskID, &u.UserID, &u.Filename, &u.Size, &u.Offset, &u.CreatedAt, &u.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// CreateAttachmentTx records a stored attachment within a transaction.
+func (pdb *PostgresDB) CreateAttachmentTx(ctx context.Context, tx *sql.Tx, a *models.Attachment) error {
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO task_attachments (task_id, user_id, filename, content_type, size, blob_key)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		a.TaskID, a.UserID, a.Filename, a.ContentType, a.Size, a.BlobKey).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return nil
+}
+
+// GetAttachmentTx retrieves an attachment of a task by its ID within a transaction.
+// It returns nil when the task has no such attachment.
+func (pdb *PostgresDB) GetAttachmentTx(ctx context.Context, tx *sql.Tx, taskID, id int32) (*models.Attachment, error) {
+	a, err := scanAttachment(tx.QueryRowContext(ctx,
+		"SELECT "+attachmentColumns+" FROM task_attachments WHERE id = $1 AND task_id = $2", id, taskID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Attachment not found
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return a, nil
+}
+
+// ListAttachmentsTx retrieves the attachments of a task within a transaction, oldest first.
+func (pdb *PostgresDB) ListAttachmentsTx(ctx context.Context, tx *sql.Tx, taskID int32) ([]*models.Attachment, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+attachmentColumns+" FROM task_attachments WHERE task_id = $1 ORDER BY created_at, id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []*models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment row: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachmentTx deletes an attachment within a transaction. Its blob is queued for deletion.
+func (pdb *PostgresDB) DeleteAttachmentTx(ctx context.Context, tx *sql.Tx, id int32) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM task_attachments WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// LockUserStorageTx serializes quota checks of a user until the transaction ends.
+func (pdb *PostgresDB) LockUserStorageTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('attachments:' || $1::text))", userID); err != nil {
+		return fmt.Errorf("failed to lock user storage: %w", err)
+	}
+	return nil
+}
+
+// UserStorageUsageTx returns the bytes a user's attachments and pending uploads take within a transaction.
+func (pdb *PostgresDB) UserStorageUsageTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (int64, error) {
+	var used int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE((SELECT SUM(size) FROM task_attachments WHERE user_id = $1), 0)
+			+ COALESCE((SELECT SUM(size) FROM attachment_uploads WHERE user_id = $1), 0)`, userID).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	return used, nil
+}
+
+// CreateAttachmentUploadTx starts a resumable upload within a transaction.
+func (pdb *PostgresDB) CreateAttachmentUploadTx(ctx context.Context, tx *sql.Tx, u *models.AttachmentUpload) error {
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO attachment_uploads (id, task_id, user_id, filename, size, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at`,
+		u.ID, u.TaskID, u.UserID, u.Filename, u.Size, u.ExpiresAt).Scan(&u.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+	return nil
+}
+
+// GetAttachmentUploadTx retrieves an unexpired upload of the user to a task within a transaction,
+// locking it when forUpdate is set. It returns nil when there is no such upload.
+func (pdb *PostgresDB) GetAttachmentUploadTx(ctx context.Context, tx *sql.Tx, taskID int32, id, userID uuid.UUID, forUpdate bool) (*models.AttachmentUpload, error) {
+	query := "SELECT " + uploadColumns + " FROM attachment_uploads WHERE id = $1 AND task_id = $2 AND user_id = $3 AND expires_at > NOW()"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	u, err := scanUpload(tx.QueryRowContext(ctx, query, id, taskID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Upload not found
+		}
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	return u, nil
+}
+
+// SetUploadOffsetTx records the bytes received for an upload within a transaction.
+func (pdb *PostgresDB) SetUploadOffsetTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, offset int64) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE attachment_uploads SET received = $1 WHERE id = $2", offset, id); err != nil {
+		return fmt.Errorf("failed to update upload: %w", err)
+	}
+	return nil
+}
+
+// DeleteAttachmentUploadTx deletes an upload within a transaction.
+func (pdb *PostgresDB) DeleteAttachmentUploadTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM attachment_uploads WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteExpiredUploadsTx deletes the uploads that expired before now within a transaction.
+func (pdb *PostgresDB) DeleteExpiredUploadsTx(ctx context.Context, tx *sql.Tx, now time.Time) (int64, error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM attachment_uploads WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired uploads: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// AttachmentUploadExistsTx reports whether an upload is still recorded within a transaction.
+func (pdb *PostgresDB) AttachmentUploadExistsTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM attachment_uploads WHERE id = $1)", id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check upload: %w", err)
+	}
+	return exists, nil
+}
+
+// ListBlobDeletionsTx locks and retrieves up to limit blob keys queued for deletion within a transaction,
+// oldest first. Keys locked by a concurrent cleaner are skipped.
+func (pdb *PostgresDB) ListBlobDeletionsTx(ctx context.Context, tx *sql.Tx, limit int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT blob_key FROM blob_deletions ORDER BY created_at LIMIT $1 FOR UPDATE SKIP LOCKED", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blob deletions: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan blob deletion row: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return keys, nil
+}
+
+// DeleteBlobDeletionTx removes a blob key from the deletion queue within a transaction.
+func (pdb *PostgresDB) DeleteBlobDeletionTx(ctx context.Context, tx *sql.Tx, key string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM blob_deletions WHERE blob_key = $1", key); err != nil {
+		return fmt.Errorf("failed to delete blob deletion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// commentColumns is the column list matching scanComment.
+const commentColumns = "id, task_id, user_id, body, created_at, updated_at, edited_at"
+
+// scanComment scans a row selected with commentColumns into a comment.
+func scanComment(row rowScanner) (*models.TaskComment, error) {
+	c := &models.TaskComment{}
+	if err := row.Scan(&c.ID, &c.TaskID, &c.UserID, &c.Body, &c.CreatedAt, &c.UpdatedAt, &c.EditedAt); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// collectComments scans all rows selected with commentColumns.
+func collectComments(rows *sql.Rows) ([]*models.TaskComment, error) {
+	comments := []*models.TaskComment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return comments, nil
+}
+
+// CreateTaskCommentTx creates a new comment within a transaction.
+func (pdb *PostgresDB) CreateTaskCommentTx(ctx context.Context, tx *sql.Tx, comment *models.TaskComment) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO task_comments (task_id, user_id, body) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at",
+		comment.TaskID, comment.UserID, comment.Body).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	return nil
+}
+
+// GetTaskCommentTx retrieves a comment of a task by its ID within a transaction.
+// It returns nil when the task has no such comment.
+func (pdb *PostgresDB) GetTaskCommentTx(ctx context.Context, tx *sql.Tx, taskID, id int32) (*models.TaskComment, error) {
+	comment, err := scanComment(tx.QueryRowContext(ctx,
+		"SELECT "+commentColumns+" FROM task_comments WHERE id = $1 AND task_id = $2 FOR UPDATE", id, taskID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Comment not found
+		}
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return comment, nil
+}
+
+// ListTaskCommentsTx retrieves the comments of a task within a transaction, oldest first.
+func (pdb *PostgresDB) ListTaskCommentsTx(ctx context.Context, tx *sql.Tx, taskID int32) ([]*models.TaskComment, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+commentColumns+" FROM task_comments WHERE task_id = $1 ORDER BY created_at, id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	return collectComments(rows)
+}
+
+// ListUserCommentsTx retrieves every comment written by a user within a transaction, oldest first.
+func (pdb *PostgresDB) ListUserCommentsTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]*models.TaskComment, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+commentColumns+" FROM task_comments WHERE user_id = $1 ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	return collectComments(rows)
+}
+
+// UpdateTaskCommentTx replaces the body of a comment within a transaction,
+// keeping the previous body as a revision.
+func (pdb *PostgresDB) UpdateTaskCommentTx(ctx context.Context, tx *sql.Tx, comment *models.TaskComment) error {
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO task_comment_revisions (comment_id, body, created_at) SELECT id, body, updated_at FROM task_comments WHERE id = $1",
+		comment.ID); err != nil {
+		return fmt.Errorf("failed to save comment revision: %w", err)
+	}
+
+	err := tx.QueryRowContext(ctx,
+		"UPDATE task_comments SET body = $1, edited_at = NOW(), updated_at = NOW() WHERE id = $2 RETURNING updated_at, edited_at",
+		comment.Body, comment.ID).Scan(&comment.UpdatedAt, &comment.EditedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	return nil
+}
+
+// DeleteTaskCommentTx deletes a comment with its revisions and mentions within a transaction.
+func (pdb *PostgresDB) DeleteTaskCommentTx(ctx context.Context, tx *sql.Tx, id int32) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM task_comments WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListCommentRevisionsTx retrieves the previous bodies of a comment within a transaction, oldest first.
+// Each revision carries the time its body was written.
+func (pdb *PostgresDB) ListCommentRevisionsTx(ctx context.Context, tx *sql.Tx, commentID int32) ([]*models.TaskCommentRevision, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, comment_id, body, created_at FROM task_comment_revisions WHERE comment_id = $1 ORDER BY created_at, id", commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comment revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*models.TaskCommentRevision{}
+	for rows.Next() {
+		rev := &models.TaskCommentRevision{}
+		if err := rows.Scan(&rev.ID, &rev.CommentID, &rev.Body, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment revision row: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return revisions, nil
+}
+
+// SetCommentMentionsTx replaces the users mentioned by a comment within a transaction.
+func (pdb *PostgresDB) SetCommentMentionsTx(ctx context.Context, tx *sql.Tx, commentID int32, userIDs []uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM task_comment_mentions WHERE comment_id = $1", commentID); err != nil {
+		return fmt.Errorf("failed to clear comment mentions: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		ids = append(ids, id.String())
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO task_comment_mentions (comment_id, user_id) SELECT $1, unnest($2::uuid[]) ON CONFLICT DO NOTHING",
+		commentID, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to save comment mentions: %w", err)
+	}
+	return nil
+}
+
+// LoadCommentMentionsTx fills the Mentions of the given comments within a transaction.
+func (pdb *PostgresDB) LoadCommentMentionsTx(ctx context.Context, tx *sql.Tx, comments ...*models.TaskComment) error {
+	byID := make(map[int32]*models.TaskComment, len(comments))
+	ids := make([]int32, 0, len(comments))
+	for _, c := range comments {
+		c.Mentions = []uuid.UUID{}
+		byID[c.ID] = c
+		ids = append(ids, c.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT comment_id, user_id FROM task_comment_mentions WHERE comment_id = ANY($1) ORDER BY comment_id, user_id", pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load comment mentions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID int32
+		var userID uuid.UUID
+		if err := rows.Scan(&commentID, &userID); err != nil {
+			return fmt.Errorf("failed to scan comment mention row: %w", err)
+		}
+		if c, ok := byID[commentID]; ok {
+			c.Mentions = append(c.Mentions, userID)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return nil
+}
+
+// ListTaskAudienceTx retrieves the users who may view a task within a transaction:
+// the members of its workspace, its assignee and the users it is shared with.
+func (pdb *PostgresDB) ListTaskAudienceTx(ctx context.Context, tx *sql.Tx, taskID int32) ([]*models.User, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+userColumns+` FROM users WHERE id IN (
+			SELECT m.user_id FROM workspace_members m JOIN tasks t ON t.workspace_id = m.workspace_id WHERE t.id = $1
+			UNION SELECT assignee_id FROM tasks WHERE id = $1 AND assignee_id IS NOT NULL
+			UNION SELECT user_id FROM task_shares WHERE task_id = $1)
+		ORDER BY created_at, id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task audience: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return users, nil
+}
+
+// CreateTaskActivityTx records an event in the activity feed of a task within a transaction.
+func (pdb *PostgresDB) CreateTaskActivityTx(ctx context.Context, tx *sql.Tx, event *models.TaskActivityEvent) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO task_activity (task_id, user_id, kind, old_value, new_value) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		event.TaskID, event.UserID, event.Kind, event.OldValue, event.NewValue).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record task activity: %w", err)
+	}
+	return nil
+}
+
+// ListTaskActivityTx retrieves the recorded events of a task within a transaction, oldest first.
+func (pdb *PostgresDB) ListTaskActivityTx(ctx context.Context, tx *sql.Tx, taskID int32) ([]*models.TaskActivityEvent, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, task_id, user_id, kind, old_value, new_value, created_at FROM task_activity WHERE task_id = $1 ORDER BY created_at, id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task activity: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.TaskActivityEvent{}
+	for rows.Next() {
+		e := &models.TaskActivityEvent{}
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.UserID, &e.Kind, &e.OldValue, &e.NewValue, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task activity row: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return events, nil
+}
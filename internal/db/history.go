@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// SetAuditContextTx names the user and request making the changes of a transaction,
+// which the history trigger on tasks records with each change.
+func (pdb *PostgresDB) SetAuditContextTx(ctx context.Context, tx *sql.Tx, actorID uuid.UUID, requestID string) error {
+	_, err := tx.ExecContext(ctx,
+		"SELECT set_config('taskmanager.actor_id', $1, true), set_config('taskmanager.request_id', $2, true)",
+		actorID.String(), requestID)
+	if err != nil {
+		return fmt.Errorf("failed to set audit context: %w", err)
+	}
+	return nil
+}
+
+// ListTaskEventsTx retrieves the recorded history of a task within a transaction, oldest first.
+func (pdb *PostgresDB) ListTaskEventsTx(ctx context.Context, tx *sql.Tx, taskID int32) ([]*models.TaskEvent, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, task_id, actor_id, request_id, action, version, changes, created_at FROM task_events WHERE task_id = $1 ORDER BY id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.TaskEvent{}
+	for rows.Next() {
+		e := &models.TaskEvent{}
+		var changes []byte
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.ActorID, &e.RequestID, &e.Action, &e.Version, &changes, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task event row: %w", err)
+		}
+		if err := json.Unmarshal(changes, &e.Changes); err != nil {
+			return nil, fmt.Errorf("failed to decode task event changes: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// ClaimIdempotencyKeyTx stores a new, in-progress idempotency key within a transaction.
+// An expired key with the same name is taken over. It returns false when a live key already exists.
+func (pdb *PostgresDB) ClaimIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, rec *models.IdempotencyRecord) (bool, error) {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status_code = NULL, response_headers = NULL, response_body = NULL,
+				created_at = NOW(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= NOW()
+		RETURNING created_at`,
+		rec.UserID, rec.Key, rec.RequestHash, rec.ExpiresAt).Scan(&rec.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil // Key is held by another request
+		}
+		return false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	return true, nil
+}
+
+// GetIdempotencyKeyTx retrieves a stored idempotency key within a transaction.
+func (pdb *PostgresDB) GetIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	rec := &models.IdempotencyRecord{}
+	var headers []byte
+	err := tx.QueryRowContext(ctx,
+		"SELECT user_id, key, request_hash, status_code, response_headers, response_body, created_at, expires_at FROM idempotency_keys WHERE user_id = $1 AND key = $2",
+		userID, key).
+		Scan(&rec.UserID, &rec.Key, &rec.RequestHash, &rec.StatusCode, &headers, &rec.ResponseBody, &rec.CreatedAt, &rec.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Key not found
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if headers != nil {
+		if err := json.Unmarshal(headers, &rec.ResponseHeaders); err != nil {
+			return nil, fmt.Errorf("failed to decode stored response headers: %w", err)
+		}
+	}
+	return rec, nil
+}
+
+// CompleteIdempotencyKeyTx stores the response of the request holding the key within a transaction
+// and extends the key until rec.ExpiresAt. A key whose response is already stored is left untouched.
+func (pdb *PostgresDB) CompleteIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, rec *models.IdempotencyRecord) error {
+	headers, err := json.Marshal(rec.ResponseHeaders)
+	if err != nil {
+		return fmt.Errorf("failed to encode response headers: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status_code = $1, response_headers = $2, response_body = $3, expires_at = $4 WHERE user_id = $5 AND key = $6 AND status_code IS NULL",
+		rec.StatusCode, headers, rec.ResponseBody, rec.ExpiresAt, rec.UserID, rec.Key)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteIdempotencyKeyTx removes an in-progress key within a transaction so that the request can be retried.
+func (pdb *PostgresDB) DeleteIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, key string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL", userID, key); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeysTx purges all expired keys within a transaction and returns their number.
+func (pdb *PostgresDB) DeleteExpiredIdempotencyKeysTx(ctx context.Context, tx *sql.Tx) (int64, error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected, nil
+}
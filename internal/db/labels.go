@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the PostgreSQL error code for unique constraint violations.
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+const labelColumns = "id, user_id, workspace_id, name, color, created_at, updated_at"
+
+// scanLabel scans a row selected with labelColumns into a label.
+func scanLabel(row rowScanner, extra ...any) (*models.Label, error) {
+	label := &models.Label{}
+	dest := []any{&label.ID, &label.UserID, &label.WorkspaceID, &label.Name, &label.Color, &label.CreatedAt, &label.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// CreateLabelTx creates a new label within a transaction.
+func (pdb *PostgresDB) CreateLabelTx(ctx context.Context, tx *sql.Tx, label *models.Label) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO labels (user_id, workspace_id, name, color) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at",
+		label.UserID, label.WorkspaceID, label.Name, label.Color).Scan(&label.ID, &label.CreatedAt, &label.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("label %q: %w", label.Name, models.ErrAlreadyExists)
+		}
+		return fmt.Errorf("failed to create label: %w", err)
+	}
+	return nil
+}
+
+// GetLabelTx retrieves a label by its ID within a transaction, if the user is a member of its workspace.
+func (pdb *PostgresDB) GetLabelTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Label, error) {
+	label, err := scanLabel(tx.QueryRowContext(ctx,
+		"SELECT "+labelColumns+" FROM labels WHERE id = $1 AND "+readableBy("workspace_id", "$2"), id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Label not found
+		}
+		return nil, fmt.Errorf("failed to get label: %w", err)
+	}
+	return label, nil
+}
+
+// ListLabelsTx retrieves the labels of the user's workspaces ordered by name within a transaction.
+// A non-nil workspaceID restricts the list to that workspace.
+func (pdb *PostgresDB) ListLabelsTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, workspaceID *int32) ([]*models.Label, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+labelColumns+" FROM labels WHERE "+readableBy("workspace_id", "$1")+" AND ($2::integer IS NULL OR workspace_id = $2) ORDER BY lower(name), id",
+		userID, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := []*models.Label{}
+	for rows.Next() {
+		label, err := scanLabel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan label row: %w", err)
+		}
+		labels = append(labels, label)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return labels, nil
+}
+
+// UpdateLabelTx renames or recolors a label within a transaction, if label.UserID may edit its workspace.
+// Tasks reference labels by ID, so a rename is visible on every task at once and bumps their versions.
+func (pdb *PostgresDB) UpdateLabelTx(ctx context.Context, tx *sql.Tx, label *models.Label) error {
+	err := tx.QueryRowContext(ctx,
+		"UPDATE labels SET name = $1, color = $2, updated_at = NOW() WHERE id = $3 AND "+writableBy("workspace_id", "$4")+" RETURNING user_id, workspace_id, created_at, updated_at",
+		label.Name, label.Color, label.ID, label.UserID).Scan(&label.UserID, &label.WorkspaceID, &label.CreatedAt, &label.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		if isUniqueViolation(err) {
+			return fmt.Errorf("label %q: %w", label.Name, models.ErrAlreadyExists)
+		}
+		return fmt.Errorf("failed to update label: %w", err)
+	}
+	return pdb.touchLabelledTasksTx(ctx, tx, label.ID)
+}
+
+// DeleteLabelTx deletes a label by its ID within a transaction, if the user may edit its workspace.
+// The label is detached from all tasks by the foreign key cascade; their versions are bumped
+// and the change of their labels recorded in their history.
+func (pdb *PostgresDB) DeleteLabelTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `
+		WITH touched AS (
+			UPDATE tasks t SET updated_at = NOW(), version = t.version + 1 FROM (
+				SELECT task_id, jsonb_agg(label_id ORDER BY label_id) AS old_ids,
+					coalesce(jsonb_agg(label_id ORDER BY label_id) FILTER (WHERE label_id <> $1), '[]') AS new_ids
+				FROM task_labels WHERE task_id IN (
+					SELECT tl.task_id FROM task_labels tl JOIN labels l ON l.id = tl.label_id WHERE l.id = $1 AND `+writableBy("l.workspace_id", "$2")+`)
+				GROUP BY task_id
+			) labels
+			WHERE t.id = labels.task_id
+			RETURNING t.id, t.user_id, t.version, labels.old_ids, labels.new_ids
+		)`+labelEventSQL,
+		id, userID); err != nil {
+		return fmt.Errorf("failed to touch labelled tasks: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM labels WHERE id = $1 AND "+writableBy("workspace_id", "$2"), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete label: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetTaskLabelsTx replaces the labels of a task within a transaction, bumping the task's version
+// when they change. All labels must belong to the task's workspace, otherwise an ErrInvalidInput error is returned.
+func (pdb *PostgresDB) SetTaskLabelsTx(ctx context.Context, tx *sql.Tx, taskID int32, userID uuid.UUID, labelIDs []int32) error {
+	var prevIDs []int32
+	if err := tx.QueryRowContext(ctx,
+		"WITH detached AS (DELETE FROM task_labels WHERE task_id = $1 RETURNING label_id) SELECT coalesce(array_agg(label_id), '{}') FROM detached",
+		taskID).Scan(pq.Array(&prevIDs)); err != nil {
+		return fmt.Errorf("failed to detach task labels: %w", err)
+	}
+
+	ids := uniqueInt32(labelIDs)
+	if len(ids) > 0 {
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO task_labels (task_id, label_id) SELECT $1, id FROM labels
+			WHERE id = ANY($2) AND workspace_id = (SELECT workspace_id FROM tasks WHERE id = $1) AND `+readableBy("workspace_id", "$3"),
+			taskID, pq.Array(ids), userID)
+		if err != nil {
+			return fmt.Errorf("failed to attach task labels: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected != int64(len(ids)) {
+			return fmt.Errorf("%w: unknown label in label_ids", models.ErrInvalidInput)
+		}
+	}
+	return pdb.touchTaskTx(ctx, tx, taskID, prevIDs)
+}
+
+// AttachTaskLabelTx attaches a single label to a task within a transaction, bumping the task's version.
+// The user must be able to edit the task and the label must be in the task's workspace,
+// otherwise sql.ErrNoRows is returned.
+func (pdb *PostgresDB) AttachTaskLabelTx(ctx context.Context, tx *sql.Tx, taskID, labelID int32, userID uuid.UUID) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM tasks t JOIN labels l ON l.workspace_id = t.workspace_id
+			WHERE t.id = $1 AND l.id = $2 AND `+writableBy("t.workspace_id", "$3")+`)`,
+		taskID, labelID, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check task and label: %w", err)
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	prevIDs, err := taskLabelIDsTx(ctx, tx, taskID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO task_labels (task_id, label_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		taskID, labelID)
+	if err != nil {
+		return fmt.Errorf("failed to attach label: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil // Already attached
+	}
+	return pdb.touchTaskTx(ctx, tx, taskID, prevIDs)
+}
+
+// DetachTaskLabelTx removes a label from a task within a transaction, if the user may edit the task's workspace,
+// and bumps the task's version.
+func (pdb *PostgresDB) DetachTaskLabelTx(ctx context.Context, tx *sql.Tx, taskID, labelID int32, userID uuid.UUID) error {
+	prevIDs, err := taskLabelIDsTx(ctx, tx, taskID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM task_labels tl USING tasks t
+		WHERE tl.task_id = t.id AND tl.task_id = $1 AND tl.label_id = $2 AND `+writableBy("t.workspace_id", "$3"),
+		taskID, labelID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to detach label: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return pdb.touchTaskTx(ctx, tx, taskID, prevIDs)
+}
+
+// LoadTaskLabelsTx fills the Labels field of the given tasks within a transaction.
+func (pdb *PostgresDB) LoadTaskLabelsTx(ctx context.Context, tx *sql.Tx, tasks ...*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int32]*models.Task, len(tasks))
+	ids := make([]int32, 0, len(tasks))
+	for _, task := range tasks {
+		task.Labels = []*models.Label{}
+		byID[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT l.id, l.user_id, l.workspace_id, l.name, l.color, l.created_at, l.updated_at, tl.task_id
+		FROM task_labels tl JOIN labels l ON l.id = tl.label_id
+		WHERE tl.task_id = ANY($1)
+		ORDER BY lower(l.name)`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load task labels: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int32
+		label, err := scanLabel(rows, &taskID)
+		if err != nil {
+			return fmt.Errorf("failed to scan task label row: %w", err)
+		}
+		if task, ok := byID[taskID]; ok {
+			task.Labels = append(task.Labels, label)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return nil
+}
+
+// labelEventSQL records the change of label_ids of the tasks in touched, with the columns id, user_id,
+// version, old_ids and new_ids, in their history. Labels are not part of the task row, so the history
+// trigger on tasks leaves these changes to the application.
+const labelEventSQL = `
+	INSERT INTO task_events (task_id, owner_id, actor_id, request_id, action, version, changes)
+	SELECT id, user_id, NULLIF(current_setting('taskmanager.actor_id', true), '')::uuid,
+		NULLIF(current_setting('taskmanager.request_id', true), ''), 'updated', version,
+		jsonb_build_object('label_ids', jsonb_build_object('old', old_ids, 'new', new_ids))
+	FROM touched`
+
+// taskLabelIDsTx returns the IDs of the labels attached to a task.
+func taskLabelIDsTx(ctx context.Context, tx *sql.Tx, taskID int32) ([]int32, error) {
+	var ids []int32
+	if err := tx.QueryRowContext(ctx,
+		"SELECT coalesce(array_agg(label_id), '{}') FROM task_labels WHERE task_id = $1", taskID).Scan(pq.Array(&ids)); err != nil {
+		return nil, fmt.Errorf("failed to get task label ids: %w", err)
+	}
+	return ids, nil
+}
+
+// touchTaskTx bumps the version and update time of a task whose labels were prevIDs before,
+// so that ETags and sync clients see the change, and records the change in its history.
+// Nothing happens when the labels are the same again.
+func (pdb *PostgresDB) touchTaskTx(ctx context.Context, tx *sql.Tx, taskID int32, prevIDs []int32) error {
+	if _, err := tx.ExecContext(ctx, `
+		WITH labels AS (
+			SELECT (SELECT coalesce(jsonb_agg(id ORDER BY id), '[]') FROM unnest($2::integer[]) AS prev(id)) AS old_ids,
+				coalesce(jsonb_agg(label_id ORDER BY label_id), '[]') AS new_ids
+			FROM task_labels WHERE task_id = $1
+		), touched AS (
+			UPDATE tasks t SET updated_at = NOW(), version = t.version + 1 FROM labels
+			WHERE t.id = $1 AND labels.old_ids <> labels.new_ids
+			RETURNING t.id, t.user_id, t.version, labels.old_ids, labels.new_ids
+		)`+labelEventSQL,
+		taskID, pq.Array(prevIDs)); err != nil {
+		return fmt.Errorf("failed to touch task: %w", err)
+	}
+	return nil
+}
+
+// touchLabelledTasksTx bumps the versions of all tasks carrying a label. Their label IDs stay the same,
+// so the bump adds nothing to their history.
+func (pdb *PostgresDB) touchLabelledTasksTx(ctx context.Context, tx *sql.Tx, labelID int32) error {
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE tasks SET updated_at = NOW(), version = version + 1 WHERE id IN (SELECT task_id FROM task_labels WHERE label_id = $1)",
+		labelID); err != nil {
+		return fmt.Errorf("failed to touch labelled tasks: %w", err)
+	}
+	return nil
+}
+
+// uniqueInt32 returns ids without duplicates, preserving order.
+func uniqueInt32(ids []int32) []int32 {
+	seen := make(map[int32]struct{}, len(ids))
+	out := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_tasks_user_completed;
+DROP INDEX IF EXISTS idx_tasks_user_title;
+DROP INDEX IF EXISTS idx_tasks_user_due_date;
+DROP INDEX IF EXISTS idx_tasks_user_updated_at;
+DROP INDEX IF EXISTS idx_tasks_user_created_at;
+
+COMMIT;
\ No newline at end of file
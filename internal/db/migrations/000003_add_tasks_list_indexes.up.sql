@@ -0,0 +1,9 @@
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_created_at ON tasks (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_updated_at ON tasks (user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_due_date ON tasks (user_id, (COALESCE(due_date, 'infinity'::timestamp)), id);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_title ON tasks (user_id, title, id);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_completed ON tasks (user_id, completed);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_tasks_search_vector;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector;
+
+COMMIT;
\ No newline at end of file
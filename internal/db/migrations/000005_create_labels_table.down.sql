@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS task_labels;
+
+DROP TABLE IF EXISTS labels;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS project_id;
+
+DROP TABLE IF EXISTS projects;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS projects (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_inbox BOOLEAN NOT NULL DEFAULT FALSE,
+    archived_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_user_inbox ON projects (user_id) WHERE is_inbox;
+
+ALTER TABLE tasks ADD COLUMN project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_project_id ON tasks (project_id);
+
+-- Every existing user gets an Inbox holding their current tasks.
+INSERT INTO projects (user_id, name, is_inbox)
+SELECT id, 'Inbox', TRUE FROM users;
+
+UPDATE tasks t SET project_id = p.id
+FROM projects p
+WHERE p.user_id = t.user_id AND p.is_inbox;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS parent_id;
+
+COMMIT;
\ No newline at end of file
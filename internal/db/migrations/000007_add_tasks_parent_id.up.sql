@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE tasks ADD COLUMN parent_id INTEGER REFERENCES tasks(id) ON DELETE CASCADE;
+
+ALTER TABLE tasks ADD CONSTRAINT tasks_parent_not_self CHECK (parent_id IS NULL OR parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks (parent_id);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS task_series;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,19 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS task_series (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recurrence_rule TEXT NOT NULL,
+    dtstart TIMESTAMP NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    ended_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE tasks ADD COLUMN series_id UUID REFERENCES task_series(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_series_id ON tasks (series_id);
+
+COMMIT;
\ No newline at end of file
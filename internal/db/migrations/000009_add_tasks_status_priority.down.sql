@@ -0,0 +1,18 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_tasks_user_priority;
+DROP INDEX IF EXISTS idx_tasks_user_status;
+DROP INDEX IF EXISTS idx_tasks_user_completed;
+
+ALTER TABLE tasks DROP COLUMN completed;
+ALTER TABLE tasks ADD COLUMN completed BOOLEAN DEFAULT FALSE;
+UPDATE tasks SET completed = (status = 'done');
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_completed ON tasks (user_id, completed);
+
+ALTER TABLE tasks
+    DROP COLUMN completed_at,
+    DROP COLUMN priority,
+    DROP COLUMN status;
+
+COMMIT;
\ No newline at end of file
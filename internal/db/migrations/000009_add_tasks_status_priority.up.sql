@@ -0,0 +1,21 @@
+BEGIN;
+
+ALTER TABLE tasks
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'todo'
+        CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled')),
+    ADD COLUMN priority VARCHAR(8) NOT NULL DEFAULT 'none'
+        CHECK (priority IN ('none', 'low', 'medium', 'high', 'urgent')),
+    ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE tasks SET status = 'done', completed_at = updated_at WHERE completed;
+
+-- completed is kept for existing queries and clients, but is now derived from status.
+DROP INDEX IF EXISTS idx_tasks_user_completed;
+ALTER TABLE tasks DROP COLUMN completed;
+ALTER TABLE tasks ADD COLUMN completed BOOLEAN GENERATED ALWAYS AS (status = 'done') STORED;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_completed ON tasks (user_id, completed);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_status ON tasks (user_id, status);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_priority ON tasks (user_id, priority);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE users DROP COLUMN IF EXISTS time_zone;
+
+DROP INDEX IF EXISTS idx_tasks_user_due_date;
+ALTER TABLE tasks DROP COLUMN IF EXISTS due_all_day;
+ALTER TABLE task_series ALTER COLUMN dtstart TYPE TIMESTAMP USING dtstart AT TIME ZONE 'UTC';
+ALTER TABLE tasks ALTER COLUMN due_date TYPE TIMESTAMP USING due_date AT TIME ZONE 'UTC';
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_due_date ON tasks (user_id, (COALESCE(due_date, 'infinity'::timestamp)), id);
+
+COMMIT;
\ No newline at end of file
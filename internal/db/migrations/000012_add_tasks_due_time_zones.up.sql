@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Existing values were written as UTC wall-clock time.
+DROP INDEX IF EXISTS idx_tasks_user_due_date;
+ALTER TABLE tasks ALTER COLUMN due_date TYPE TIMESTAMP WITH TIME ZONE USING due_date AT TIME ZONE 'UTC';
+ALTER TABLE task_series ALTER COLUMN dtstart TYPE TIMESTAMP WITH TIME ZONE USING dtstart AT TIME ZONE 'UTC';
+
+-- Tasks created without a due date were stored as the zero time.
+UPDATE tasks SET due_date = NULL WHERE due_date < '0002-01-01T00:00:00Z';
+
+ALTER TABLE tasks ADD COLUMN due_all_day BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_due_date ON tasks (user_id, (COALESCE(due_date, 'infinity'::timestamptz)), id);
+
+ALTER TABLE users ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_settings;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS email;
+
+COMMIT;
\ No newline at end of file
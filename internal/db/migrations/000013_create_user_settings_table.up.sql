@@ -0,0 +1,23 @@
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(320) NOT NULL DEFAULT '',
+    ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    locale VARCHAR(35) NOT NULL DEFAULT 'en',
+    week_start SMALLINT NOT NULL DEFAULT 1 CHECK (week_start BETWEEN 0 AND 6),
+    default_project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL,
+    notify_email BOOLEAN NOT NULL DEFAULT TRUE,
+    notify_due_reminders BOOLEAN NOT NULL DEFAULT TRUE,
+    notify_mentions BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO user_settings (user_id) SELECT id FROM users;
+
+COMMIT;
\ No newline at end of file
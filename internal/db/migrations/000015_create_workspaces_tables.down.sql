@@ -0,0 +1,43 @@
+BEGIN;
+
+-- Data of shared workspaces cannot be represented per user and is dropped.
+DELETE FROM tasks WHERE workspace_id IN (SELECT id FROM workspaces WHERE NOT is_personal);
+DELETE FROM task_series WHERE workspace_id IN (SELECT id FROM workspaces WHERE NOT is_personal);
+DELETE FROM projects WHERE workspace_id IN (SELECT id FROM workspaces WHERE NOT is_personal);
+DELETE FROM labels WHERE workspace_id IN (SELECT id FROM workspaces WHERE NOT is_personal);
+
+-- Rows whose creator was deleted have no owner to return to.
+DELETE FROM tasks WHERE user_id IS NULL;
+DELETE FROM task_series WHERE user_id IS NULL;
+DELETE FROM projects WHERE user_id IS NULL;
+DELETE FROM labels WHERE user_id IS NULL;
+
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_user_id_fkey,
+    ADD CONSTRAINT tasks_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_user_id_fkey, ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT projects_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE labels DROP CONSTRAINT IF EXISTS labels_user_id_fkey, ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT labels_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE task_series DROP CONSTRAINT IF EXISTS task_series_user_id_fkey, ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT task_series_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_tasks_workspace_due_date;
+DROP INDEX IF EXISTS idx_tasks_workspace_created_at;
+DROP INDEX IF EXISTS idx_task_series_workspace_id;
+DROP INDEX IF EXISTS idx_projects_workspace_id;
+DROP INDEX IF EXISTS idx_projects_workspace_inbox;
+DROP INDEX IF EXISTS idx_labels_workspace_name;
+
+ALTER TABLE task_series DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE labels DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE projects DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS workspace_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_user_inbox ON projects (user_id) WHERE is_inbox;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_labels_user_name ON labels (user_id, lower(name));
+
+DROP TABLE IF EXISTS workspace_invitations;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
+
+COMMIT;
\ No newline at end of file
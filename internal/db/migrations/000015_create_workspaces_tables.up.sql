@@ -0,0 +1,88 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS workspaces (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    is_personal BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every user has exactly one personal workspace.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspaces_personal ON workspaces (created_by) WHERE is_personal;
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members (user_id);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    email VARCHAR(320) NOT NULL,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('admin', 'member', 'viewer')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    declined_at TIMESTAMP WITH TIME ZONE
+);
+
+-- An email has at most one open invitation per workspace.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_invitations_open ON workspace_invitations (workspace_id, lower(email))
+    WHERE accepted_at IS NULL AND declined_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_workspace_invitations_email ON workspace_invitations (lower(email));
+
+-- Every existing user gets a personal workspace holding all of their data.
+INSERT INTO workspaces (name, is_personal, created_by)
+SELECT 'Personal', TRUE, id FROM users;
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, created_by, 'owner' FROM workspaces WHERE is_personal;
+
+-- Tasks from before users existed cannot be reached by anyone.
+DELETE FROM tasks WHERE user_id IS NULL;
+
+ALTER TABLE tasks ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE projects ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE labels ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE task_series ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+
+UPDATE tasks t SET workspace_id = w.id FROM workspaces w WHERE w.is_personal AND w.created_by = t.user_id;
+UPDATE projects p SET workspace_id = w.id FROM workspaces w WHERE w.is_personal AND w.created_by = p.user_id;
+UPDATE labels l SET workspace_id = w.id FROM workspaces w WHERE w.is_personal AND w.created_by = l.user_id;
+UPDATE task_series s SET workspace_id = w.id FROM workspaces w WHERE w.is_personal AND w.created_by = s.user_id;
+
+ALTER TABLE tasks ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE projects ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE labels ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE task_series ALTER COLUMN workspace_id SET NOT NULL;
+
+-- Content of shared workspaces outlives its creator, personal workspaces are deleted with the user.
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_user_id_fkey,
+    ADD CONSTRAINT tasks_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE projects ALTER COLUMN user_id DROP NOT NULL, DROP CONSTRAINT IF EXISTS projects_user_id_fkey,
+    ADD CONSTRAINT projects_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE labels ALTER COLUMN user_id DROP NOT NULL, DROP CONSTRAINT IF EXISTS labels_user_id_fkey,
+    ADD CONSTRAINT labels_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE task_series ALTER COLUMN user_id DROP NOT NULL, DROP CONSTRAINT IF EXISTS task_series_user_id_fkey,
+    ADD CONSTRAINT task_series_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- Names and the Inbox are unique per workspace instead of per user.
+DROP INDEX IF EXISTS idx_labels_user_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_labels_workspace_name ON labels (workspace_id, lower(name));
+DROP INDEX IF EXISTS idx_projects_user_inbox;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_workspace_inbox ON projects (workspace_id) WHERE is_inbox;
+
+CREATE INDEX IF NOT EXISTS idx_projects_workspace_id ON projects (workspace_id);
+CREATE INDEX IF NOT EXISTS idx_task_series_workspace_id ON task_series (workspace_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_workspace_created_at ON tasks (workspace_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_tasks_workspace_due_date ON tasks (workspace_id, (COALESCE(due_date, 'infinity'::timestamptz)), id);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS task_shares;
+
+DROP INDEX IF EXISTS idx_tasks_assignee_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS assignee_id;
+
+COMMIT;
\ No newline at end of file
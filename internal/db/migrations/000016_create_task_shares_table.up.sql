@@ -0,0 +1,20 @@
+BEGIN;
+
+-- The assignee is the user a task is handed to; assigning grants edit access to the task.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS assignee_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_assignee_id ON tasks (assignee_id) WHERE assignee_id IS NOT NULL;
+
+-- Shares grant single tasks to users outside the task's workspace.
+CREATE TABLE IF NOT EXISTS task_shares (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    permission VARCHAR(16) NOT NULL CHECK (permission IN ('view', 'edit')),
+    shared_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_shares_user_id ON task_shares (user_id);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS task_activity;
+DROP TABLE IF EXISTS task_comment_mentions;
+DROP TABLE IF EXISTS task_comment_revisions;
+DROP TABLE IF EXISTS task_comments;
+
+COMMIT;
\ No newline at end of file
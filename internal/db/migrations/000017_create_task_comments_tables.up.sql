@@ -0,0 +1,47 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS task_comments (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_comments_task_id ON task_comments (task_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_task_comments_user_id ON task_comments (user_id);
+
+-- Previous bodies of edited comments, one row per edit.
+CREATE TABLE IF NOT EXISTS task_comment_revisions (
+    id SERIAL PRIMARY KEY,
+    comment_id INTEGER NOT NULL REFERENCES task_comments(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_comment_revisions_comment_id ON task_comment_revisions (comment_id);
+
+CREATE TABLE IF NOT EXISTS task_comment_mentions (
+    comment_id INTEGER NOT NULL REFERENCES task_comments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_comment_mentions_user_id ON task_comment_mentions (user_id);
+
+-- System events shown in a task's activity feed next to its comments.
+CREATE TABLE IF NOT EXISTS task_activity (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    kind VARCHAR(32) NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_activity_task_id ON task_activity (task_id, created_at);
+
+COMMIT;
\ No newline at end of file
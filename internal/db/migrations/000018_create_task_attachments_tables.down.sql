@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS trg_task_attachments_blob_deletion ON task_attachments;
+DROP FUNCTION IF EXISTS queue_attachment_blob_deletion();
+DROP TABLE IF EXISTS blob_deletions;
+DROP TABLE IF EXISTS attachment_uploads;
+DROP TABLE IF EXISTS task_attachments;
+
+COMMIT;
\ No newline at end of file
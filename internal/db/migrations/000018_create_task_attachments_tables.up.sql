@@ -0,0 +1,50 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS task_attachments (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL CHECK (size >= 0),
+    blob_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_attachments_task_id ON task_attachments (task_id);
+CREATE INDEX IF NOT EXISTS idx_task_attachments_user_id ON task_attachments (user_id);
+
+-- Resumable uploads in progress. Their chunks are staged on local disk until the last one arrives.
+CREATE TABLE IF NOT EXISTS attachment_uploads (
+    id UUID PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL CHECK (size >= 0),
+    received BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachment_uploads_user_id ON attachment_uploads (user_id);
+CREATE INDEX IF NOT EXISTS idx_attachment_uploads_expires_at ON attachment_uploads (expires_at);
+
+-- Blobs of deleted attachments waiting to be removed from the blob store. The trigger also
+-- catches attachments deleted through cascades from tasks, projects, workspaces and users.
+CREATE TABLE IF NOT EXISTS blob_deletions (
+    blob_key VARCHAR(255) PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION queue_attachment_blob_deletion() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO blob_deletions (blob_key) VALUES (OLD.blob_key) ON CONFLICT DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_task_attachments_blob_deletion
+    AFTER DELETE ON task_attachments
+    FOR EACH ROW EXECUTE FUNCTION queue_attachment_blob_deletion();
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS trg_tasks_record_event ON tasks;
+DROP FUNCTION IF EXISTS record_task_event();
+DROP TABLE IF EXISTS task_events;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,80 @@
+BEGIN;
+
+-- Audit history of tasks: one row per create, update and delete of a task row, with the
+-- changed fields as {"field": {"old": ..., "new": ...}}. Rows are written by a trigger, so
+-- every change is recorded in the transaction making it. The task ID has no foreign key
+-- because the history outlives tasks deleted by their users.
+CREATE TABLE IF NOT EXISTS task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    -- The task's creator; deleting their account removes the history of their tasks.
+    owner_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    request_id TEXT,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('created', 'updated', 'deleted')),
+    version INTEGER NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON task_events (task_id, id);
+CREATE INDEX IF NOT EXISTS idx_task_events_owner_id ON task_events (owner_id);
+CREATE INDEX IF NOT EXISTS idx_task_events_actor_id ON task_events (actor_id);
+
+-- The application names the acting user and request in the transaction-local settings
+-- taskmanager.actor_id and taskmanager.request_id; changes made without them have no actor.
+CREATE OR REPLACE FUNCTION record_task_event() RETURNS trigger AS $$
+DECLARE
+    ignored TEXT[] := ARRAY['id', 'search_vector', 'completed', 'created_at', 'updated_at', 'version'];
+    actor UUID := NULLIF(current_setting('taskmanager.actor_id', true), '')::uuid;
+    request TEXT := NULLIF(current_setting('taskmanager.request_id', true), '');
+    diff JSONB;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT COALESCE(jsonb_object_agg(key, jsonb_build_object('old', NULL, 'new', value)), '{}')
+        INTO diff
+        FROM jsonb_each(jsonb_strip_nulls(to_jsonb(NEW) - ignored));
+
+        INSERT INTO task_events (task_id, owner_id, actor_id, request_id, action, version, changes)
+        VALUES (NEW.id, NEW.user_id, actor, request, 'created', NEW.version, diff);
+        RETURN NEW;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        -- Foreign key actions (a deleted series or project) do not make a new version of the task.
+        IF NEW.version = OLD.version THEN
+            RETURN NEW;
+        END IF;
+
+        SELECT COALESCE(jsonb_object_agg(n.key, jsonb_build_object('old', o.value, 'new', n.value)), '{}')
+        INTO diff
+        FROM jsonb_each(to_jsonb(NEW) - ignored) n
+        JOIN jsonb_each(to_jsonb(OLD) - ignored) o ON o.key = n.key
+        WHERE n.value IS DISTINCT FROM o.value;
+
+        INSERT INTO task_events (task_id, owner_id, actor_id, request_id, action, version, changes)
+        VALUES (NEW.id, NEW.user_id, actor, request, 'updated', NEW.version, diff);
+        RETURN NEW;
+    END IF;
+
+    -- A task deleted along with its parent, workspace or owner takes its history with it.
+    IF pg_trigger_depth() > 1 THEN
+        DELETE FROM task_events WHERE task_id = OLD.id;
+        RETURN OLD;
+    END IF;
+
+    SELECT COALESCE(jsonb_object_agg(key, jsonb_build_object('old', value, 'new', NULL)), '{}')
+    INTO diff
+    FROM jsonb_each(jsonb_strip_nulls(to_jsonb(OLD) - ignored));
+
+    INSERT INTO task_events (task_id, owner_id, actor_id, request_id, action, version, changes)
+    VALUES (OLD.id, OLD.user_id, actor, request, 'deleted', OLD.version, diff);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_tasks_record_event
+    AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_task_event();
+
+COMMIT;
\ No newline at end of file
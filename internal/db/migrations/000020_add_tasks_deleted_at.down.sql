@@ -0,0 +1,63 @@
+BEGIN;
+
+CREATE OR REPLACE FUNCTION record_task_event() RETURNS trigger AS $$
+DECLARE
+    ignored TEXT[] := ARRAY['id', 'search_vector', 'completed', 'created_at', 'updated_at', 'version'];
+    actor UUID := NULLIF(current_setting('taskmanager.actor_id', true), '')::uuid;
+    request TEXT := NULLIF(current_setting('taskmanager.request_id', true), '');
+    diff JSONB;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT COALESCE(jsonb_object_agg(key, jsonb_build_object('old', NULL, 'new', value)), '{}')
+        INTO diff
+        FROM jsonb_each(jsonb_strip_nulls(to_jsonb(NEW) - ignored));
+
+        INSERT INTO task_events (task_id, owner_id, actor_id, request_id, action, version, changes)
+        VALUES (NEW.id, NEW.user_id, actor, request, 'created', NEW.version, diff);
+        RETURN NEW;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        -- Foreign key actions (a deleted series or project) do not make a new version of the task.
+        IF NEW.version = OLD.version THEN
+            RETURN NEW;
+        END IF;
+
+        SELECT COALESCE(jsonb_object_agg(n.key, jsonb_build_object('old', o.value, 'new', n.value)), '{}')
+        INTO diff
+        FROM jsonb_each(to_jsonb(NEW) - ignored) n
+        JOIN jsonb_each(to_jsonb(OLD) - ignored) o ON o.key = n.key
+        WHERE n.value IS DISTINCT FROM o.value;
+
+        INSERT INTO task_events (task_id, owner_id, actor_id, request_id, action, version, changes)
+        VALUES (NEW.id, NEW.user_id, actor, request, 'updated', NEW.version, diff);
+        RETURN NEW;
+    END IF;
+
+    -- A task deleted along with its parent, workspace or owner takes its history with it.
+    IF pg_trigger_depth() > 1 THEN
+        DELETE FROM task_events WHERE task_id = OLD.id;
+        RETURN OLD;
+    END IF;
+
+    SELECT COALESCE(jsonb_object_agg(key, jsonb_build_object('old', value, 'new', NULL)), '{}')
+    INTO diff
+    FROM jsonb_each(jsonb_strip_nulls(to_jsonb(OLD) - ignored));
+
+    INSERT INTO task_events (task_id, owner_id, actor_id, request_id, action, version, changes)
+    VALUES (OLD.id, OLD.user_id, actor, request, 'deleted', OLD.version, diff);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE task_events SET action = 'updated' WHERE action IN ('trashed', 'restored');
+ALTER TABLE task_events DROP CONSTRAINT IF EXISTS task_events_action_check;
+ALTER TABLE task_events ADD CONSTRAINT task_events_action_check
+    CHECK (action IN ('created', 'updated', 'deleted'));
+
+-- Tombstones would reappear as live tasks.
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_tasks_deleted_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
+
+COMMIT;
\ No newline at end of file
@@ -6,6 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/HellUpa/taskmanager/internal/config"
 	"github.com/HellUpa/taskmanager/internal/models"
@@ -13,6 +16,7 @@ import (
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type PostgresDB struct {
@@ -49,23 +53,39 @@ func NewPostgresDB(log *slog.Logger, cfg config.DatabaseConfig) (*PostgresDB, er
 	return &PostgresDB{DB: db, log: log}, nil
 }
 
+// userColumns is the column list matching scanUser.
+const userColumns = "id, kratos_id, email, display_name, time_zone, created_at, updated_at"
+
+// scanUser scans a row selected with userColumns into a user.
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	if err := row.Scan(&user.ID, &user.KratosID, &user.Email, &user.DisplayName, &user.TimeZone, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 // CreateUserTx creates a new user within a transaction.
-func (pdb *PostgresDB) CreateUserTx(ctx context.Context, tx *sql.Tx, user *models.User) error {
-	_, err := tx.ExecContext(ctx,
-		"INSERT INTO users (id, kratos_id) VALUES ($1, $2)",
-		user.ID, user.KratosID)
+// It reports false, leaving the user untouched, when a user with the same Kratos ID already exists.
+func (pdb *PostgresDB) CreateUserTx(ctx context.Context, tx *sql.Tx, user *models.User) (bool, error) {
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO users (id, kratos_id, email, display_name, time_zone) VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'UTC'))
+		ON CONFLICT (kratos_id) DO NOTHING
+		RETURNING time_zone, created_at, updated_at`,
+		user.ID, user.KratosID, user.Email, user.DisplayName, user.TimeZone).Scan(&user.TimeZone, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil // User already exists
+		}
+		return false, fmt.Errorf("failed to create user: %w", err)
 	}
-	return nil
+	return true, nil
 }
 
 // GetUserByKratosIDTx retrieves a user by their Kratos ID within a transaction.
 func (pdb *PostgresDB) GetUserByKratosIDTx(ctx context.Context, tx *sql.Tx, kratosID string) (*models.User, error) {
-	user := &models.User{}
-	err := tx.QueryRowContext(ctx,
-		"SELECT id, kratos_id FROM users WHERE kratos_id = $1", kratosID).
-		Scan(&user.ID, &user.KratosID)
+	user, err := scanUser(tx.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE kratos_id = $1", kratosID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // User not found
@@ -77,10 +97,8 @@ func (pdb *PostgresDB) GetUserByKratosIDTx(ctx context.Context, tx *sql.Tx, krat
 
 // GetUserByIDTx retrieves a user by their ID within a transaction.
 func (pdb *PostgresDB) GetUserByIDTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.User, error) {
-	user := &models.User{}
-	err := tx.QueryRowContext(ctx,
-		"SELECT id, kratos_id FROM users WHERE id = $1", id).
-		Scan(&user.ID, &user.KratosID)
+	user, err := scanUser(tx.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE id = $1", id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // User not found
@@ -90,24 +108,91 @@ func (pdb *PostgresDB) GetUserByIDTx(ctx context.Context, tx *sql.Tx, id uuid.UU
 	return user, nil
 }
 
+// GetUserByEmailTx retrieves a user by their email, compared case-insensitively, within a transaction.
+func (pdb *PostgresDB) GetUserByEmailTx(ctx context.Context, tx *sql.Tx, email string) (*models.User, error) {
+	user, err := scanUser(tx.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE lower(email) = lower($1) ORDER BY created_at LIMIT 1", email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // User not found
+		}
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	return user, nil
+}
+
+// UpdateUserProfileTx updates the editable profile fields of a user within a transaction.
+func (pdb *PostgresDB) UpdateUserProfileTx(ctx context.Context, tx *sql.Tx, user *models.User) error {
+	err := tx.QueryRowContext(ctx,
+		"UPDATE users SET display_name = $1, updated_at = NOW() WHERE id = $2 RETURNING "+userColumns,
+		user.DisplayName, user.ID).
+		Scan(&user.ID, &user.KratosID, &user.Email, &user.DisplayName, &user.TimeZone, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to update user profile: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserTraitsTx copies the Kratos identity traits onto the user within a transaction.
+// An empty display name is filled from the email.
+func (pdb *PostgresDB) UpdateUserTraitsTx(ctx context.Context, tx *sql.Tx, kratosID string, traits models.IdentityTraits) error {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE users SET email = $1, display_name = COALESCE(NULLIF(display_name, ''), $2), updated_at = NOW() WHERE kratos_id = $3",
+		traits.Email, models.DefaultDisplayName(traits.Email), kratosID)
+	if err != nil {
+		return fmt.Errorf("failed to update user traits: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// taskColumns is the column list matching scanTask.
+const taskColumns = "id, user_id, workspace_id, assignee_id, project_id, parent_id, series_id, title, description, due_date, due_all_day, status, priority, completed, completed_at, created_at, updated_at, version, deleted_at"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask scans a row selected with taskColumns into a task.
+// Extra destinations receive any columns selected after taskColumns.
+func scanTask(row rowScanner, extra ...any) (*models.Task, error) {
+	task := &models.Task{}
+	dest := []any{&task.ID, &task.UserID, &task.WorkspaceID, &task.AssigneeID, &task.ProjectID, &task.ParentID, &task.SeriesID, &task.Title, &task.Description, &task.DueDate, &task.DueAllDay, &task.Status, &task.Priority, &task.Completed, &task.CompletedAt, &task.CreatedAt, &task.UpdatedAt, &task.Version, &task.DeletedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
 // CreateTaskTx creates a new task within a transaction.
 func (pdb *PostgresDB) CreateTaskTx(ctx context.Context, tx *sql.Tx, task *models.Task) (int32, error) {
 	var id int32
 	err := tx.QueryRowContext(ctx,
-		"INSERT INTO tasks (title, description, due_date, user_id) VALUES ($1, $2, $3, $4) RETURNING id",
-		task.Title, task.Description, task.DueDate, task.UserID).Scan(&id)
+		`INSERT INTO tasks (title, description, due_date, due_all_day, user_id, workspace_id, project_id, parent_id, series_id, status, priority, completed_at, assignee_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CASE WHEN $10 = 'done' THEN NOW() END, $12) RETURNING id, version`,
+		task.Title, task.Description, task.DueDate, task.DueAllDay, task.UserID, task.WorkspaceID, task.ProjectID, task.ParentID, task.SeriesID, task.Status, task.Priority, task.AssigneeID).Scan(&id, &task.Version)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create task: %w", err)
 	}
 	return id, nil
 }
 
-// GetTaskTx retrieves a task by its ID within a transaction.
+// GetTaskTx retrieves a task by its ID within a transaction, if the user may view it.
 func (pdb *PostgresDB) GetTaskTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Task, error) {
-	task := &models.Task{}
-	err := tx.QueryRowContext(ctx,
-		"SELECT id, user_id, title, description, due_date, completed, created_at, updated_at FROM tasks WHERE id = $1 AND user_id = $2", id, userID).
-		Scan(&task.ID, &task.UserID, &task.Title, &task.Description, &task.DueDate, &task.Completed, &task.CreatedAt, &task.UpdatedAt)
+	task, err := scanTask(tx.QueryRowContext(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE id = $1 AND deleted_at IS NULL AND "+taskReadableBy("$2"), id, userID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // Task not found
@@ -117,68 +202,320 @@ func (pdb *PostgresDB) GetTaskTx(ctx context.Context, tx *sql.Tx, id int32, user
 	return task, nil
 }
 
-// UpdateTaskTx updates an existing task within a transaction, and checks user ownership.
-func (pdb *PostgresDB) UpdateTaskTx(ctx context.Context, tx *sql.Tx, task *models.Task) error {
-	result, err := tx.ExecContext(ctx,
-		"UPDATE tasks SET title = $1, description = $2, due_date = $3, completed = $4, updated_at = NOW() WHERE id = $5 AND user_id = $6",
-		task.Title, task.Description, task.DueDate, task.Completed, task.ID, task.UserID)
+// UpdateTaskTx updates an existing task within a transaction, if task.UserID may edit it.
+// A nil ProjectID, ParentID or SeriesID keeps the current value. A new parent is rejected
+// with ErrInvalidInput when it would make the task its own ancestor. CompletedAt is set
+// when the task becomes done and cleared when it leaves that status.
+// With a non-nil ifMatch the row is only updated while its version is one of the given ones,
+// otherwise models.ErrPreconditionFailed is returned. Users who only view the task get models.ErrForbidden.
+func (pdb *PostgresDB) UpdateTaskTx(ctx context.Context, tx *sql.Tx, task *models.Task, ifMatch []int32) error {
+	if task.ParentID != nil {
+		if err := pdb.checkTaskParentTx(ctx, tx, task.ID, *task.ParentID, task.UserID); err != nil {
+			return err
+		}
+	}
+
+	err := tx.QueryRowContext(ctx,
+		`UPDATE tasks SET title = $1, description = $2, due_date = $3, due_all_day = $12, status = $4, priority = $5,
+			completed_at = CASE WHEN $4 = 'done' THEN COALESCE(completed_at, NOW()) END,
+			project_id = COALESCE($6, project_id), parent_id = COALESCE($7, parent_id), series_id = COALESCE($8, series_id),
+			updated_at = NOW(), version = version + 1
+		WHERE id = $9 AND deleted_at IS NULL AND `+taskWritableBy("$10")+` AND ($11::integer[] IS NULL OR version = ANY($11))
+		RETURNING workspace_id, assignee_id, project_id, parent_id, series_id, completed, completed_at, created_at, updated_at, version`,
+		task.Title, task.Description, task.DueDate, task.Status, task.Priority, task.ProjectID, task.ParentID, task.SeriesID, task.ID, task.UserID, pq.Array(ifMatch), task.DueAllDay).
+		Scan(&task.WorkspaceID, &task.AssigneeID, &task.ProjectID, &task.ParentID, &task.SeriesID, &task.Completed, &task.CompletedAt, &task.CreatedAt, &task.UpdatedAt, &task.Version)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pdb.taskMissingOrChangedTx(ctx, tx, task.ID, task.UserID, models.TaskAccessEdit, ifMatch)
+		}
 		return fmt.Errorf("failed to update task: %w", err)
 	}
 
+	return nil
+}
+
+// DeleteTaskTx moves a task with its subtasks to the trash within a transaction, if the user owns it.
+// All of them get the same deletion time, by which RestoreTaskTx brings them back together.
+// A non-nil ifMatch restricts the delete to the given versions like in UpdateTaskTx.
+func (pdb *PostgresDB) DeleteTaskTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID, ifMatch []int32) error {
+	result, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM tasks
+			WHERE id = $1 AND deleted_at IS NULL AND `+taskOwnedBy("$2")+` AND ($3::integer[] IS NULL OR version = ANY($3))
+			UNION
+			SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL
+		)
+		UPDATE tasks SET deleted_at = NOW(), updated_at = NOW(), version = version + 1
+		WHERE id IN (SELECT id FROM subtree)`,
+		id, userID, pq.Array(ifMatch))
+	if err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
+	}
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return pdb.taskMissingOrChangedTx(ctx, tx, id, userID, models.TaskAccessOwner, ifMatch)
 	}
 
 	return nil
 }
 
-// DeleteTaskTx deletes a task by its ID within a transaction, and checks user ownership.
-func (pdb *PostgresDB) DeleteTaskTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) error {
-	result, err := tx.ExecContext(ctx, "DELETE FROM tasks WHERE id = $1 AND user_id = $2", id, userID)
+// taskMissingOrChangedTx tells why a write on a task affected no rows: sql.ErrNoRows when the
+// task does not exist for the user, models.ErrForbidden when the user's access to it is below
+// required and models.ErrPreconditionFailed when its version differs.
+func (pdb *PostgresDB) taskMissingOrChangedTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID, required string, ifMatch []int32) error {
+	access, err := pdb.GetTaskAccessTx(ctx, tx, id, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete task: %w", err)
+		return err
+	}
+	if access == "" {
+		return sql.ErrNoRows
+	}
+	if !models.TaskAccessAllows(access, required) {
+		return fmt.Errorf("task %d: %w", id, models.ErrForbidden)
 	}
+	if ifMatch == nil {
+		return sql.ErrNoRows
+	}
+	return models.ErrPreconditionFailed
+}
 
-	rowsAffected, err := result.RowsAffected()
+// taskSortColumns maps the allowed sort fields to their SQL expressions.
+var taskSortColumns = map[string]string{
+	models.TaskSortCreatedAt: "created_at",
+	models.TaskSortUpdatedAt: "updated_at",
+	models.TaskSortDueDate:   "COALESCE(due_date, 'infinity'::timestamptz)",
+	models.TaskSortTitle:     "title",
+	models.TaskSortPriority:  "array_position(ARRAY['none', 'low', 'medium', 'high', 'urgent']::varchar[], priority)",
+	models.TaskSortID:        "id",
+}
+
+// ListTasksTx retrieves a page of the tasks in the user's workspaces, or of the tasks assigned
+// or shared to the user as selected by opts.Relation, within a transaction.
+// It fetches one extra row to detect whether a next page exists.
+func (pdb *PostgresDB) ListTasksTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, opts *models.TaskListOptions) ([]*models.Task, bool, error) {
+	args := []any{userID}
+	where := []string{readableBy("workspace_id", "$1")}
+	switch opts.Relation {
+	case models.TaskRelationAssigned:
+		where = []string{"assignee_id = $1"}
+	case models.TaskRelationShared:
+		where = []string{"id IN (SELECT task_id FROM task_shares WHERE user_id = $1)"}
+	}
+	addFilter := func(cond string, value any) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if !opts.IncludeDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+	if opts.WorkspaceID != nil {
+		addFilter("workspace_id = $%d", *opts.WorkspaceID)
+	}
+	if opts.ProjectID != nil {
+		addFilter("project_id = $%d", *opts.ProjectID)
+	}
+	if opts.Completed != nil {
+		addFilter("completed = $%d", *opts.Completed)
+	}
+	if len(opts.Statuses) > 0 {
+		addFilter("status = ANY($%d)", pq.Array(opts.Statuses))
+	}
+	if len(opts.Priorities) > 0 {
+		addFilter("priority = ANY($%d)", pq.Array(opts.Priorities))
+	}
+	if opts.Due != "" {
+		where = append(where, taskDueCondition(opts.Due, time.Now(), opts.Location, func(value any) string {
+			args = append(args, value)
+			return fmt.Sprintf("$%d", len(args))
+		}))
+	}
+	if opts.DueBefore != nil {
+		addFilter("due_date < $%d", *opts.DueBefore)
+	}
+	if opts.DueAfter != nil {
+		addFilter("due_date >= $%d", *opts.DueAfter)
+	}
+	if opts.CreatedBefore != nil {
+		addFilter("created_at < $%d", *opts.CreatedBefore)
+	}
+	if opts.CreatedAfter != nil {
+		addFilter("created_at >= $%d", *opts.CreatedAfter)
+	}
+	if opts.UpdatedBefore != nil {
+		addFilter("updated_at < $%d", *opts.UpdatedBefore)
+	}
+	if opts.UpdatedAfter != nil {
+		addFilter("updated_at >= $%d", *opts.UpdatedAfter)
+	}
+	for _, name := range opts.Labels {
+		// Labels belong to a workspace, so only the task's own workspace can hold a matching label.
+		addFilter(`EXISTS (SELECT 1 FROM task_labels tl JOIN labels l ON l.id = tl.label_id
+			WHERE tl.task_id = tasks.id AND l.workspace_id = tasks.workspace_id AND lower(l.name) = lower($%d))`, name)
+	}
+
+	sortExpr, ok := taskSortColumns[opts.SortBy]
+	if !ok {
+		return nil, false, fmt.Errorf("unsupported sort field %q", opts.SortBy)
+	}
+	direction, cmp := "ASC", ">"
+	if opts.SortDesc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if opts.Cursor != nil {
+		value, err := taskCursorValue(opts.Cursor)
+		if err != nil {
+			return nil, false, err
+		}
+		if opts.SortBy == models.TaskSortID {
+			addFilter("id "+cmp+" $%d", opts.Cursor.ID)
+		} else {
+			args = append(args, value, opts.Cursor.ID)
+			where = append(where, fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortExpr, cmp, len(args)-1, len(args)))
+		}
+	}
+
+	args = append(args, opts.Limit+1)
+	query := fmt.Sprintf("SELECT %s FROM tasks WHERE %s ORDER BY %s %s, id %s LIMIT $%d",
+		taskColumns, strings.Join(where, " AND "), sortExpr, direction, direction, len(args))
+
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, false, fmt.Errorf("failed to list tasks: %w", err)
 	}
+	defer rows.Close()
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	tasks := make([]*models.Task, 0, opts.Limit)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to scan task row: %w", err)
+		}
+		tasks = append(tasks, task)
 	}
 
-	return nil
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	hasMore := len(tasks) > opts.Limit
+	if hasMore {
+		tasks = tasks[:opts.Limit]
+	}
+	return tasks, hasMore, nil
+}
+
+// taskDueCondition returns the WHERE condition of a due filter, arg binds a value and returns its placeholder.
+// All-day due dates are stored as midnight UTC of their day and are compared with today's calendar date,
+// timed ones are compared with the bounds of today in loc.
+func taskDueCondition(due string, now time.Time, loc *time.Location, arg func(any) string) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	y, m, d := now.In(loc).Date()
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	switch due {
+	case models.TaskDueToday:
+		dayStart := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		return fmt.Sprintf("((due_all_day AND due_date = %s) OR (NOT due_all_day AND due_date >= %s AND due_date < %s))",
+			arg(date), arg(dayStart), arg(dayEnd))
+	case models.TaskDueOverdue:
+		return fmt.Sprintf("(status NOT IN ('done', 'cancelled') AND ((due_all_day AND due_date < %s) OR (NOT due_all_day AND due_date < %s)))",
+			arg(date), arg(now))
+	}
+	return "due_date IS NULL"
+}
+
+// taskCursorValue converts the cursor value into the type of its sort column.
+func taskCursorValue(c *models.TaskCursor) (any, error) {
+	switch c.SortBy {
+	case models.TaskSortCreatedAt, models.TaskSortUpdatedAt, models.TaskSortDueDate:
+		if c.SortBy == models.TaskSortDueDate && c.Value == "infinity" {
+			return c.Value, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		return t, nil
+	case models.TaskSortTitle:
+		return c.Value, nil
+	case models.TaskSortPriority:
+		rank := models.TaskPriorityRank(c.Value)
+		if rank < 0 {
+			return nil, models.ErrInvalidCursor
+		}
+		return rank + 1, nil // array_position is 1-based
+	}
+	return nil, nil
 }
 
-// ListTasksTx retrieves all tasks for a specific user within a transaction.
-func (pdb *PostgresDB) ListTasksTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]*models.Task, error) {
-	rows, err := tx.QueryContext(ctx, "SELECT id, user_id, title, description, due_date, completed, created_at, updated_at FROM tasks WHERE user_id = $1", userID)
+// SearchTasksTx runs a ranked full-text search over the tasks in the user's workspaces within a transaction.
+// Every term of the query is matched as a prefix, so partially typed words match too.
+func (pdb *PostgresDB) SearchTasksTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, query string, limit int) ([]*models.TaskSearchResult, error) {
+	tsQuery := buildPrefixTSQuery(query)
+	if tsQuery == "" {
+		return []*models.TaskSearchResult{}, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+taskColumns+`,
+			ts_rank_cd(search_vector, q) AS rank,
+			ts_headline('simple', `+htmlEscapeSQL("title")+`, q, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+			ts_headline('simple', `+htmlEscapeSQL("coalesce(description, '')")+`, q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=3, MaxWords=20, MinWords=5')
+		FROM tasks, to_tsquery('simple', $2) AS q
+		WHERE `+readableBy("workspace_id", "$1")+` AND deleted_at IS NULL AND search_vector @@ q
+		ORDER BY rank DESC, id DESC
+		LIMIT $3`, userID, tsQuery, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tasks: %w", err)
+		return nil, fmt.Errorf("failed to search tasks: %w", err)
 	}
 	defer rows.Close()
 
-	var tasks []*models.Task
+	results := []*models.TaskSearchResult{}
 	for rows.Next() {
-		task := &models.Task{}
-		if err := rows.Scan(&task.ID, &task.UserID, &task.Title, &task.Description, &task.DueDate, &task.Completed, &task.CreatedAt, &task.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan task row: %w", err)
+		result := &models.TaskSearchResult{}
+		task, err := scanTask(rows, &result.Rank, &result.TitleHighlight, &result.DescriptionHighlight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search row: %w", err)
 		}
-		tasks = append(tasks, task)
+		result.Task = task
+		results = append(results, result)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error during rows iteration: %w", err)
 	}
 
-	return tasks, nil
+	return results, nil
+}
+
+// htmlEscapeSQL returns an SQL expression escaping the HTML special characters of the text expr,
+// so that highlights only contain the <mark> tags added by ts_headline.
+func htmlEscapeSQL(expr string) string {
+	return "replace(replace(replace(replace(" + expr + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '\"', '&quot;')"
+}
+
+// buildPrefixTSQuery turns free text into a tsquery string where each word is
+// a prefix term and all terms must match, e.g. "buy mil" -> "buy:* & mil:*".
+// Characters with a meaning in tsquery syntax are dropped.
+func buildPrefixTSQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, strings.ToLower(w)+":*")
+	}
+	return strings.Join(terms, " & ")
 }
 
 // Close closes the database connection.
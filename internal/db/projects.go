@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+const projectColumns = "id, user_id, workspace_id, name, description, is_inbox, archived_at, created_at, updated_at"
+
+// scanProject scans a row selected with projectColumns into a project.
+func scanProject(row rowScanner) (*models.Project, error) {
+	project := &models.Project{}
+	if err := row.Scan(&project.ID, &project.UserID, &project.WorkspaceID, &project.Name, &project.Description, &project.IsInbox,
+		&project.ArchivedAt, &project.CreatedAt, &project.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// CreateProjectTx creates a new project within a transaction.
+func (pdb *PostgresDB) CreateProjectTx(ctx context.Context, tx *sql.Tx, project *models.Project) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO projects (user_id, workspace_id, name, description, is_inbox) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at",
+		project.UserID, project.WorkspaceID, project.Name, project.Description, project.IsInbox).
+		Scan(&project.ID, &project.CreatedAt, &project.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("inbox project: %w", models.ErrAlreadyExists)
+		}
+		return fmt.Errorf("failed to create project: %w", err)
+	}
+	return nil
+}
+
+// GetProjectTx retrieves a project by its ID within a transaction, if the user is a member of its workspace.
+func (pdb *PostgresDB) GetProjectTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Project, error) {
+	project, err := scanProject(tx.QueryRowContext(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE id = $1 AND "+readableBy("workspace_id", "$2"), id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Project not found
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	return project, nil
+}
+
+// GetInboxProjectTx retrieves the Inbox project of a workspace within a transaction.
+func (pdb *PostgresDB) GetInboxProjectTx(ctx context.Context, tx *sql.Tx, workspaceID int32) (*models.Project, error) {
+	project, err := scanProject(tx.QueryRowContext(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE workspace_id = $1 AND is_inbox", workspaceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Workspace has no inbox
+		}
+		return nil, fmt.Errorf("failed to get inbox project: %w", err)
+	}
+	return project, nil
+}
+
+// ListProjectsTx retrieves the projects of the user's workspaces within a transaction.
+// Archived projects are only included when includeArchived is set, a non-nil workspaceID
+// restricts the list to that workspace.
+func (pdb *PostgresDB) ListProjectsTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, workspaceID *int32, includeArchived bool) ([]*models.Project, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE "+readableBy("workspace_id", "$1")+
+			" AND ($2::integer IS NULL OR workspace_id = $2) AND ($3 OR archived_at IS NULL) ORDER BY workspace_id, is_inbox DESC, lower(name), id",
+		userID, workspaceID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	defer rows.Close()
+
+	projects := []*models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project row: %w", err)
+		}
+		projects = append(projects, project)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return projects, nil
+}
+
+// UpdateProjectTx updates the name and description of a project within a transaction, if project.UserID may edit its workspace.
+func (pdb *PostgresDB) UpdateProjectTx(ctx context.Context, tx *sql.Tx, project *models.Project) error {
+	updated, err := scanProject(tx.QueryRowContext(ctx,
+		"UPDATE projects SET name = $1, description = $2, updated_at = NOW() WHERE id = $3 AND "+writableBy("workspace_id", "$4")+" RETURNING "+projectColumns,
+		project.Name, project.Description, project.ID, project.UserID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to update project: %w", err)
+	}
+	*project = *updated
+	return nil
+}
+
+// SetProjectArchivedTx archives or unarchives a project within a transaction, if the user may edit its workspace.
+func (pdb *PostgresDB) SetProjectArchivedTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID, archived bool) (*models.Project, error) {
+	project, err := scanProject(tx.QueryRowContext(ctx, `
+		UPDATE projects SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, NOW()) END, updated_at = NOW()
+		WHERE id = $2 AND `+writableBy("workspace_id", "$3")+`
+		RETURNING `+projectColumns, archived, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to archive project: %w", err)
+	}
+	return project, nil
+}
+
+// DeleteProjectTx deletes a project by its ID within a transaction, if the user may edit its workspace.
+// Remaining tasks are detached by the foreign key, callers move or delete them beforehand.
+func (pdb *PostgresDB) DeleteProjectTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM projects WHERE id = $1 AND "+writableBy("workspace_id", "$2"), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// MoveProjectTasksTx moves all tasks of one project, including those in the trash, into another within a transaction.
+func (pdb *PostgresDB) MoveProjectTasksTx(ctx context.Context, tx *sql.Tx, fromID, toID int32, userID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE tasks SET project_id = $1, updated_at = NOW(), version = version + 1 WHERE project_id = $2 AND "+writableBy("workspace_id", "$3"),
+		toID, fromID, userID); err != nil {
+		return fmt.Errorf("failed to move project tasks: %w", err)
+	}
+	return nil
+}
+
+// DeleteProjectTasksTx moves all tasks of a project with their subtasks to the trash within a transaction.
+func (pdb *PostgresDB) DeleteProjectTasksTx(ctx context.Context, tx *sql.Tx, projectID int32, userID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM tasks WHERE project_id = $1 AND deleted_at IS NULL AND `+writableBy("workspace_id", "$2")+`
+			UNION
+			SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL
+		)
+		UPDATE tasks SET deleted_at = NOW(), updated_at = NOW(), version = version + 1
+		WHERE id IN (SELECT id FROM subtree)`, projectID, userID); err != nil {
+		return fmt.Errorf("failed to delete project tasks: %w", err)
+	}
+	return nil
+}
+
+// MoveTaskTx moves a single task into a project within a transaction, if the user may edit its workspace.
+func (pdb *PostgresDB) MoveTaskTx(ctx context.Context, tx *sql.Tx, taskID int32, projectID *int32, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE tasks SET project_id = $1, updated_at = NOW(), version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND "+writableBy("workspace_id", "$3"),
+		projectID, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to move task: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
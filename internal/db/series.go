@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const seriesColumns = "id, user_id, workspace_id, recurrence_rule, dtstart, title, description, ended_at, created_at, updated_at"
+
+// CreateTaskSeriesTx creates a new recurring task series within a transaction.
+func (pdb *PostgresDB) CreateTaskSeriesTx(ctx context.Context, tx *sql.Tx, series *models.TaskSeries) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO task_series (id, user_id, workspace_id, recurrence_rule, dtstart, title, description) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at, updated_at",
+		series.ID, series.UserID, series.WorkspaceID, series.RecurrenceRule, series.DTStart, series.Title, series.Description).
+		Scan(&series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create task series: %w", err)
+	}
+	return nil
+}
+
+// GetTaskSeriesTx retrieves a series by its ID within a transaction, if the user is a member of its workspace
+// or may view one of its occurrences.
+func (pdb *PostgresDB) GetTaskSeriesTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, userID uuid.UUID) (*models.TaskSeries, error) {
+	series := &models.TaskSeries{}
+	err := tx.QueryRowContext(ctx,
+		"SELECT "+seriesColumns+" FROM task_series WHERE id = $1 AND ("+readableBy("workspace_id", "$2")+
+			" OR id IN (SELECT series_id FROM tasks WHERE "+taskReadableBy("$2")+"))", id, userID).
+		Scan(&series.ID, &series.UserID, &series.WorkspaceID, &series.RecurrenceRule, &series.DTStart, &series.Title, &series.Description,
+			&series.EndedAt, &series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Series not found
+		}
+		return nil, fmt.Errorf("failed to get task series: %w", err)
+	}
+	return series, nil
+}
+
+// UpdateTaskSeriesTx updates the rule and template of a series within a transaction,
+// if userID may edit its workspace or one of its occurrences.
+func (pdb *PostgresDB) UpdateTaskSeriesTx(ctx context.Context, tx *sql.Tx, series *models.TaskSeries, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE task_series SET recurrence_rule = $1, dtstart = $2, title = $3, description = $4, ended_at = $5, updated_at = NOW() WHERE id = $6 AND ("+writableBy("workspace_id", "$7")+
+			" OR id IN (SELECT series_id FROM tasks WHERE "+taskWritableBy("$7")+"))",
+		series.RecurrenceRule, series.DTStart, series.Title, series.Description, series.EndedAt, series.ID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update task series: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateOpenOccurrencesTx copies the title and description onto all not yet completed
+// occurrences of a series, except the one given, within a transaction.
+func (pdb *PostgresDB) UpdateOpenOccurrencesTx(ctx context.Context, tx *sql.Tx, seriesID uuid.UUID, userID uuid.UUID, exceptID int32, title, description string) error {
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE tasks SET title = $1, description = $2, updated_at = NOW(), version = version + 1 WHERE series_id = $3 AND "+writableBy("workspace_id", "$4")+" AND id <> $5 AND NOT completed AND deleted_at IS NULL",
+		title, description, seriesID, userID, exceptID); err != nil {
+		return fmt.Errorf("failed to update series occurrences: %w", err)
+	}
+	return nil
+}
+
+// OccurrenceExistsTx reports whether the series already has an occurrence due at the given time.
+func (pdb *PostgresDB) OccurrenceExistsTx(ctx context.Context, tx *sql.Tx, seriesID uuid.UUID, due time.Time) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tasks WHERE series_id = $1 AND due_date = $2)", seriesID, due).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check series occurrence: %w", err)
+	}
+	return exists, nil
+}
+
+// CopyTaskLabelsTx attaches the labels of one task to another within a transaction.
+func (pdb *PostgresDB) CopyTaskLabelsTx(ctx context.Context, tx *sql.Tx, fromID, toID int32) error {
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO task_labels (task_id, label_id) SELECT $2, label_id FROM task_labels WHERE task_id = $1 ON CONFLICT DO NOTHING",
+		fromID, toID); err != nil {
+		return fmt.Errorf("failed to copy task labels: %w", err)
+	}
+	return nil
+}
+
+// LoadTaskRecurrenceTx fills the RecurrenceRule of the given tasks from their active series within a transaction.
+func (pdb *PostgresDB) LoadTaskRecurrenceTx(ctx context.Context, tx *sql.Tx, tasks ...*models.Task) error {
+	bySeries := make(map[uuid.UUID][]*models.Task)
+	ids := make([]uuid.UUID, 0, len(tasks))
+	for _, task := range tasks {
+		task.RecurrenceRule = nil
+		if task.SeriesID == nil {
+			continue
+		}
+		if _, ok := bySeries[*task.SeriesID]; !ok {
+			ids = append(ids, *task.SeriesID)
+		}
+		bySeries[*task.SeriesID] = append(bySeries[*task.SeriesID], task)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	strIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strIDs = append(strIDs, id.String())
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, recurrence_rule FROM task_series WHERE id = ANY($1::uuid[]) AND ended_at IS NULL", pq.Array(strIDs))
+	if err != nil {
+		return fmt.Errorf("failed to load task recurrence: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var rule string
+		if err := rows.Scan(&id, &rule); err != nil {
+			return fmt.Errorf("failed to scan task series row: %w", err)
+		}
+		for _, task := range bySeries[id] {
+			r := rule
+			task.RecurrenceRule = &r
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return nil
+}
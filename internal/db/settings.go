@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// GetUserSettingsTx retrieves the settings of a user within a transaction.
+// It returns nil when the user has no stored settings.
+func (pdb *PostgresDB) GetUserSettingsTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (*models.UserSettings, error) {
+	settings := &models.UserSettings{}
+	err := tx.QueryRowContext(ctx, `
+		SELECT u.time_zone, s.locale, s.week_start, s.default_project_id,
+			s.notify_email, s.notify_due_reminders, s.notify_mentions, s.updated_at
+		FROM user_settings s JOIN users u ON u.id = s.user_id
+		WHERE s.user_id = $1`, userID).
+		Scan(&settings.TimeZone, &settings.Locale, &settings.WeekStart, &settings.DefaultProjectID,
+			&settings.Notifications.Email, &settings.Notifications.DueReminders, &settings.Notifications.Mentions, &settings.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Settings not found
+		}
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveUserSettingsTx creates or replaces the settings of a user within a transaction.
+// The time zone is stored with the user.
+func (pdb *PostgresDB) SaveUserSettingsTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID, settings *models.UserSettings) error {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE users SET time_zone = $1, updated_at = NOW() WHERE id = $2", settings.TimeZone, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user time zone: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO user_settings (user_id, locale, week_start, default_project_id, notify_email, notify_due_reminders, notify_mentions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			locale = EXCLUDED.locale, week_start = EXCLUDED.week_start, default_project_id = EXCLUDED.default_project_id,
+			notify_email = EXCLUDED.notify_email, notify_due_reminders = EXCLUDED.notify_due_reminders,
+			notify_mentions = EXCLUDED.notify_mentions, updated_at = NOW()
+		RETURNING updated_at`,
+		userID, settings.Locale, settings.WeekStart, settings.DefaultProjectID,
+		settings.Notifications.Email, settings.Notifications.DueReminders, settings.Notifications.Mentions).
+		Scan(&settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save user settings: %w", err)
+	}
+	return nil
+}
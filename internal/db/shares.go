@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// taskReadableBy returns the condition that the user bound to param may view the task:
+// as a member of its workspace, as its assignee or through a share.
+func taskReadableBy(param string) string {
+	return "(" + readableBy("workspace_id", param) + " OR assignee_id = " + param +
+		" OR id IN (SELECT task_id FROM task_shares WHERE user_id = " + param + "))"
+}
+
+// taskWritableBy returns the condition that the user bound to param may edit the task:
+// as an editing member of its workspace, as its assignee or through an edit share.
+func taskWritableBy(param string) string {
+	return "(" + writableBy("workspace_id", param) + " OR assignee_id = " + param +
+		" OR id IN (SELECT task_id FROM task_shares WHERE user_id = " + param + " AND permission = 'edit'))"
+}
+
+// taskOwnedBy returns the condition that the user bound to param owns the task:
+// its creator while still editing its workspace, or an owner of the workspace.
+func taskOwnedBy(param string) string {
+	return "((user_id = " + param + " AND " + writableBy("workspace_id", param) + ")" +
+		" OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = " + param + " AND role = 'owner'))"
+}
+
+// GetTaskAccessTx returns the access level of the user on a task within a transaction,
+// see the models.TaskAccess constants. It returns "" when the task does not exist, is in the trash or is not visible to the user.
+func (pdb *PostgresDB) GetTaskAccessTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (string, error) {
+	var access string
+	err := tx.QueryRowContext(ctx, `
+		SELECT CASE
+			WHEN m.role = 'owner' OR (t.user_id = $2 AND m.role IN ('admin', 'member')) THEN 'owner'
+			WHEN m.role IN ('admin', 'member') OR t.assignee_id = $2 OR s.permission = 'edit' THEN 'edit'
+			WHEN m.role IS NOT NULL OR s.permission IS NOT NULL THEN 'view'
+			ELSE '' END
+		FROM tasks t
+		LEFT JOIN workspace_members m ON m.workspace_id = t.workspace_id AND m.user_id = $2
+		LEFT JOIN task_shares s ON s.task_id = t.id AND s.user_id = $2
+		WHERE t.id = $1 AND t.deleted_at IS NULL`, id, userID).Scan(&access)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get task access: %w", err)
+	}
+	return access, nil
+}
+
+// SetTaskAssigneeTx assigns a task to a user, or unassigns it when assigneeID is nil,
+// within a transaction. The acting user must be able to edit the task, otherwise sql.ErrNoRows is returned.
+func (pdb *PostgresDB) SetTaskAssigneeTx(ctx context.Context, tx *sql.Tx, id int32, assigneeID *uuid.UUID, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE tasks SET assignee_id = $1, updated_at = NOW(), version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND "+taskWritableBy("$3"),
+		assigneeID, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set task assignee: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// IsTaskSharedWithTx reports whether a task is shared with the user within a transaction.
+func (pdb *PostgresDB) IsTaskSharedWithTx(ctx context.Context, tx *sql.Tx, taskID int32, userID uuid.UUID) (bool, error) {
+	var shared bool
+	err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM task_shares WHERE task_id = $1 AND user_id = $2)", taskID, userID).Scan(&shared)
+	if err != nil {
+		return false, fmt.Errorf("failed to check task share: %w", err)
+	}
+	return shared, nil
+}
+
+// UpsertTaskShareTx shares a task with a user within a transaction.
+// An existing share with the same user gets the new permission.
+func (pdb *PostgresDB) UpsertTaskShareTx(ctx context.Context, tx *sql.Tx, share *models.TaskShare) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO task_shares (task_id, user_id, permission, shared_by) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (task_id, user_id) DO UPDATE SET permission = EXCLUDED.permission, shared_by = EXCLUDED.shared_by
+		RETURNING created_at`,
+		share.TaskID, share.UserID, share.Permission, share.SharedBy).Scan(&share.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to share task: %w", err)
+	}
+	return nil
+}
+
+// ListTaskSharesTx retrieves the shares of a task within a transaction, oldest first.
+func (pdb *PostgresDB) ListTaskSharesTx(ctx context.Context, tx *sql.Tx, taskID int32) ([]*models.TaskShare, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT s.task_id, s.user_id, u.email, u.display_name, s.permission, s.shared_by, s.created_at
+		FROM task_shares s JOIN users u ON u.id = s.user_id
+		WHERE s.task_id = $1
+		ORDER BY s.created_at, s.user_id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := []*models.TaskShare{}
+	for rows.Next() {
+		share := &models.TaskShare{}
+		if err := rows.Scan(&share.TaskID, &share.UserID, &share.Email, &share.DisplayName, &share.Permission, &share.SharedBy, &share.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task share row: %w", err)
+		}
+		shares = append(shares, share)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return shares, nil
+}
+
+// DeleteTaskShareTx removes the share of a task with a user within a transaction.
+func (pdb *PostgresDB) DeleteTaskShareTx(ctx context.Context, tx *sql.Tx, taskID int32, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM task_shares WHERE task_id = $1 AND user_id = $2", taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete task share: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CopyTaskSharesTx gives a task the same shares as another one within a transaction.
+func (pdb *PostgresDB) CopyTaskSharesTx(ctx context.Context, tx *sql.Tx, fromID, toID int32) error {
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO task_shares (task_id, user_id, permission, shared_by) SELECT $2, user_id, permission, shared_by FROM task_shares WHERE task_id = $1",
+		fromID, toID); err != nil {
+		return fmt.Errorf("failed to copy task shares: %w", err)
+	}
+	return nil
+}
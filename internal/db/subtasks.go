@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetTaskAncestorsTx returns the IDs of the task and all of its ancestors,
+// starting with the task itself, within a transaction.
+// An empty result means the task does not exist or is outside the user's workspaces.
+func (pdb *PostgresDB) GetTaskAncestorsTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) ([]int32, error) {
+	rows, err := tx.QueryContext(ctx, `
+		WITH RECURSIVE chain AS (
+			SELECT id, parent_id, 1 AS depth FROM tasks WHERE id = $1 AND deleted_at IS NULL AND `+readableBy("workspace_id", "$2")+`
+			UNION
+			SELECT t.id, t.parent_id, c.depth + 1 FROM tasks t JOIN chain c ON t.id = c.parent_id
+			WHERE c.depth < 1000
+		)
+		SELECT id FROM chain ORDER BY depth`, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task ancestors: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int32
+	for rows.Next() {
+		var ancestorID int32
+		if err := rows.Scan(&ancestorID); err != nil {
+			return nil, fmt.Errorf("failed to scan ancestor row: %w", err)
+		}
+		ids = append(ids, ancestorID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return ids, nil
+}
+
+// GetTaskSubtreeHeightTx returns the number of levels in the subtree rooted at
+// the task within a transaction, 1 for a task without children.
+func (pdb *PostgresDB) GetTaskSubtreeHeightTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (int, error) {
+	var height int
+	err := tx.QueryRowContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 1 AS depth FROM tasks WHERE id = $1 AND deleted_at IS NULL AND `+readableBy("workspace_id", "$2")+`
+			UNION
+			SELECT t.id, s.depth + 1 FROM tasks t JOIN subtree s ON t.parent_id = s.id
+			WHERE s.depth < 1000 AND t.deleted_at IS NULL
+		)
+		SELECT COALESCE(MAX(depth), 0) FROM subtree`, id, userID).Scan(&height)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get subtree height: %w", err)
+	}
+	return height, nil
+}
+
+// checkTaskParentTx verifies that parentID is a task of the user's workspaces in the same workspace
+// as taskID, and that making it the parent of taskID does not create a cycle.
+func (pdb *PostgresDB) checkTaskParentTx(ctx context.Context, tx *sql.Tx, taskID, parentID int32, userID uuid.UUID) error {
+	ancestors, err := pdb.GetTaskAncestorsTx(ctx, tx, parentID, userID)
+	if err != nil {
+		return err
+	}
+	if len(ancestors) == 0 {
+		return fmt.Errorf("%w: parent task %d does not exist", models.ErrInvalidInput, parentID)
+	}
+	var sameWorkspace bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM tasks p JOIN tasks t ON t.workspace_id = p.workspace_id WHERE p.id = $1 AND t.id = $2)
+			OR NOT EXISTS (SELECT 1 FROM tasks WHERE id = $2)`, parentID, taskID).Scan(&sameWorkspace); err != nil {
+		return fmt.Errorf("failed to check parent workspace: %w", err)
+	}
+	if !sameWorkspace {
+		return fmt.Errorf("%w: parent task %d is in another workspace", models.ErrInvalidInput, parentID)
+	}
+	if slices.Contains(ancestors, taskID) {
+		return fmt.Errorf("%w: task %d cannot be nested under its own subtask %d", models.ErrInvalidInput, taskID, parentID)
+	}
+	return nil
+}
+
+// SetTaskParentTx moves a task under another task, or to the top level when parentID is nil,
+// within a transaction. It checks the user may edit the task's workspace and prevents cycles.
+func (pdb *PostgresDB) SetTaskParentTx(ctx context.Context, tx *sql.Tx, id int32, parentID *int32, userID uuid.UUID) error {
+	if parentID != nil {
+		if err := pdb.checkTaskParentTx(ctx, tx, id, *parentID, userID); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE tasks SET parent_id = $1, updated_at = NOW(), version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND "+writableBy("workspace_id", "$3"),
+		parentID, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set task parent: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListTaskChildrenTx retrieves the direct subtasks of a task within a transaction.
+func (pdb *PostgresDB) ListTaskChildrenTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) ([]*models.Task, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE parent_id = $1 AND deleted_at IS NULL AND "+readableBy("workspace_id", "$2")+" ORDER BY created_at, id", id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subtasks: %w", err)
+	}
+	defer rows.Close()
+
+	return collectTasks(rows)
+}
+
+// GetTaskTreeTx retrieves a task with all of its descendants nested in Children within a transaction.
+// It returns nil when the task does not exist.
+func (pdb *PostgresDB) GetTaskTreeTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Task, []*models.Task, error) {
+	rows, err := tx.QueryContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 1 AS depth FROM tasks WHERE id = $1 AND deleted_at IS NULL AND `+readableBy("workspace_id", "$2")+`
+			UNION
+			SELECT t.id, s.depth + 1 FROM tasks t JOIN subtree s ON t.parent_id = s.id
+			WHERE s.depth < 1000 AND t.deleted_at IS NULL
+		)
+		SELECT `+taskColumns+` FROM tasks WHERE id IN (SELECT id FROM subtree) ORDER BY created_at, id`, id, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get task tree: %w", err)
+	}
+	defer rows.Close()
+
+	tasks, err := collectTasks(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[int32]*models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	root := byID[id]
+	for _, task := range tasks {
+		if task.ID == id || task.ParentID == nil {
+			continue
+		}
+		if parent, ok := byID[*task.ParentID]; ok {
+			parent.Children = append(parent.Children, task)
+		}
+	}
+	return root, tasks, nil
+}
+
+// CompleteDescendantsTx marks every open descendant of a task done within a transaction.
+// Cancelled descendants keep their status.
+func (pdb *PostgresDB) CompleteDescendantsTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 1 AS depth FROM tasks WHERE parent_id = $1 AND deleted_at IS NULL AND `+writableBy("workspace_id", "$2")+`
+			UNION
+			SELECT t.id, s.depth + 1 FROM tasks t JOIN subtree s ON t.parent_id = s.id
+			WHERE s.depth < 1000 AND t.deleted_at IS NULL
+		)
+		UPDATE tasks SET status = 'done', completed_at = NOW(), updated_at = NOW(), version = version + 1
+		WHERE id IN (SELECT id FROM subtree) AND status NOT IN ('done', 'cancelled')`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to complete subtasks: %w", err)
+	}
+	return nil
+}
+
+// LoadSubtaskProgressTx fills the Subtasks roll-up of the given tasks within a transaction.
+func (pdb *PostgresDB) LoadSubtaskProgressTx(ctx context.Context, tx *sql.Tx, tasks ...*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int32]*models.Task, len(tasks))
+	ids := make([]int32, 0, len(tasks))
+	for _, task := range tasks {
+		task.Subtasks = nil
+		byID[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT parent_id, COUNT(*), COUNT(*) FILTER (WHERE completed)
+		FROM tasks WHERE parent_id = ANY($1) AND deleted_at IS NULL
+		GROUP BY parent_id`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load subtask progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID int32
+		var total, completed int
+		if err := rows.Scan(&parentID, &total, &completed); err != nil {
+			return fmt.Errorf("failed to scan subtask progress row: %w", err)
+		}
+		if task, ok := byID[parentID]; ok {
+			task.Subtasks = models.NewSubtaskProgress(total, completed)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return nil
+}
+
+// collectTasks scans all rows selected with taskColumns.
+func collectTasks(rows *sql.Rows) ([]*models.Task, error) {
+	tasks := []*models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task row: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return tasks, nil
+}
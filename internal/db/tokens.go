@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// tokenColumns is the column list matching scanToken.
+const tokenColumns = "id, user_id, name, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at"
+
+// scanToken scans a row selected with tokenColumns into a token.
+func scanToken(row rowScanner) (*models.PersonalAccessToken, error) {
+	token := &models.PersonalAccessToken{}
+	var scopes pq.StringArray
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &scopes,
+		&token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt, &token.CreatedAt); err != nil {
+		return nil, err
+	}
+	token.Scopes = []string(scopes)
+	return token, nil
+}
+
+// CreateTokenTx stores a new personal access token by its hash within a transaction.
+func (pdb *PostgresDB) CreateTokenTx(ctx context.Context, tx *sql.Tx, token *models.PersonalAccessToken, hash string) error {
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		token.UserID, token.Name, token.Prefix, hash, pq.Array(token.Scopes), token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	return nil
+}
+
+// ListTokensTx retrieves all tokens of a user within a transaction, newest first.
+func (pdb *PostgresDB) ListTokensTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]*models.PersonalAccessToken, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+tokenColumns+" FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC, id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*models.PersonalAccessToken{}
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan token row: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeTokenTx revokes an active token within a transaction, and checks user ownership.
+func (pdb *PostgresDB) RevokeTokenTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE personal_access_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetActiveTokenByHashTx retrieves a token that is neither revoked nor expired by its hash within a transaction.
+func (pdb *PostgresDB) GetActiveTokenByHashTx(ctx context.Context, tx *sql.Tx, hash string) (*models.PersonalAccessToken, error) {
+	token, err := scanToken(tx.QueryRowContext(ctx,
+		`SELECT `+tokenColumns+` FROM personal_access_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Token unknown, revoked or expired
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	return token, nil
+}
+
+// TouchTokenTx records the use of a token within a transaction.
+// The timestamp is written at most once a minute to keep authentication cheap.
+func (pdb *PostgresDB) TouchTokenTx(ctx context.Context, tx *sql.Tx, id int32) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE personal_access_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id); err != nil {
+		return fmt.Errorf("failed to update token last use: %w", err)
+	}
+	return nil
+}
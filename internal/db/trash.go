@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// ListTrashTx retrieves the deleted tasks the user owns within a transaction, most recently deleted first.
+// Subtasks deleted along with their parent are left out, they come back with it.
+func (pdb *PostgresDB) ListTrashTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]*models.Task, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+taskColumns+` FROM tasks t
+		WHERE deleted_at IS NOT NULL AND `+taskOwnedBy("$1")+`
+			AND NOT EXISTS (SELECT 1 FROM tasks p WHERE p.id = t.parent_id AND p.deleted_at = t.deleted_at)
+		ORDER BY deleted_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	defer rows.Close()
+
+	return collectTasks(rows)
+}
+
+// GetTrashedTaskTx retrieves a deleted task the user owns within a transaction.
+// It returns nil when there is no such task in the trash.
+func (pdb *PostgresDB) GetTrashedTaskTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Task, error) {
+	task, err := scanTask(tx.QueryRowContext(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE id = $1 AND deleted_at IS NOT NULL AND "+taskOwnedBy("$2"), id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Task not in the trash
+		}
+		return nil, fmt.Errorf("failed to get deleted task: %w", err)
+	}
+	return task, nil
+}
+
+// TaskInTrashTx reports whether a task is in the trash within a transaction.
+func (pdb *PostgresDB) TaskInTrashTx(ctx context.Context, tx *sql.Tx, id int32) (bool, error) {
+	var trashed bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND deleted_at IS NOT NULL)", id).Scan(&trashed)
+	if err != nil {
+		return false, fmt.Errorf("failed to check task: %w", err)
+	}
+	return trashed, nil
+}
+
+// RestoreTaskTx takes a deleted task out of the trash within a transaction, together with
+// the subtasks deleted along with it at deletedAt.
+func (pdb *PostgresDB) RestoreTaskTx(ctx context.Context, tx *sql.Tx, id int32, deletedAt time.Time) error {
+	result, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM tasks WHERE id = $1 AND deleted_at = $2
+			UNION
+			SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at = $2
+		)
+		UPDATE tasks SET deleted_at = NULL, updated_at = NOW(), version = version + 1
+		WHERE id IN (SELECT id FROM subtree)`, id, deletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to restore task: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeTaskTx permanently deletes a task in the trash the user owns, with its subtasks, within a transaction.
+func (pdb *PostgresDB) PurgeTaskTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM tasks WHERE id = $1 AND deleted_at IS NOT NULL AND "+taskOwnedBy("$2"), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to purge task: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeDeletedTasksTx permanently deletes the tasks that went to the trash before the given time
+// within a transaction and returns their number.
+func (pdb *PostgresDB) PurgeDeletedTasksTx(ctx context.Context, tx *sql.Tx, before time.Time) (int64, error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM tasks WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted tasks: %w", err)
+	}
+	return result.RowsAffected()
+}
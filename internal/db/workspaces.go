@@ -0,0 +1,425 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// readableBy returns the condition that the workspace in column has the user bound to param as a member.
+func readableBy(column, param string) string {
+	return column + " IN (SELECT workspace_id FROM workspace_members WHERE user_id = " + param + ")"
+}
+
+// writableBy returns the condition that the user bound to param may edit the content of the workspace in column.
+func writableBy(column, param string) string {
+	return column + " IN (SELECT workspace_id FROM workspace_members WHERE user_id = " + param + " AND role <> 'viewer')"
+}
+
+// workspaceColumns is the column list matching scanWorkspace, selected from workspaces w joined with the member m.
+const workspaceColumns = "w.id, w.name, w.is_personal, w.created_by, m.role, w.created_at, w.updated_at"
+
+// scanWorkspace scans a row selected with workspaceColumns into a workspace.
+func scanWorkspace(row rowScanner) (*models.Workspace, error) {
+	w := &models.Workspace{}
+	if err := row.Scan(&w.ID, &w.Name, &w.IsPersonal, &w.CreatedBy, &w.Role, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// invitationColumns is the column list matching scanInvitation, selected from workspace_invitations i joined with workspaces w.
+const invitationColumns = "i.id, i.workspace_id, w.name, i.email, i.role, i.invited_by, i.created_at, i.expires_at, i.accepted_at, i.declined_at"
+
+// scanInvitation scans a row selected with invitationColumns into an invitation.
+func scanInvitation(row rowScanner) (*models.WorkspaceInvitation, error) {
+	inv := &models.WorkspaceInvitation{}
+	if err := row.Scan(&inv.ID, &inv.WorkspaceID, &inv.WorkspaceName, &inv.Email, &inv.Role, &inv.InvitedBy,
+		&inv.CreatedAt, &inv.ExpiresAt, &inv.AcceptedAt, &inv.DeclinedAt); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// CreateWorkspaceTx creates a new workspace within a transaction.
+// Members are added separately with AddWorkspaceMemberTx.
+func (pdb *PostgresDB) CreateWorkspaceTx(ctx context.Context, tx *sql.Tx, workspace *models.Workspace) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO workspaces (name, is_personal, created_by) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at",
+		workspace.Name, workspace.IsPersonal, workspace.CreatedBy).
+		Scan(&workspace.ID, &workspace.CreatedAt, &workspace.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("personal workspace: %w", models.ErrAlreadyExists)
+		}
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	return nil
+}
+
+// GetWorkspaceTx retrieves a workspace by its ID with the user's role within a transaction.
+// It returns nil when the workspace does not exist or the user is not a member.
+func (pdb *PostgresDB) GetWorkspaceTx(ctx context.Context, tx *sql.Tx, id int32, userID uuid.UUID) (*models.Workspace, error) {
+	workspace, err := scanWorkspace(tx.QueryRowContext(ctx,
+		"SELECT "+workspaceColumns+" FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id WHERE w.id = $1 AND m.user_id = $2",
+		id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Workspace not found
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// GetPersonalWorkspaceTx retrieves the user's personal workspace within a transaction.
+func (pdb *PostgresDB) GetPersonalWorkspaceTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (*models.Workspace, error) {
+	workspace, err := scanWorkspace(tx.QueryRowContext(ctx,
+		"SELECT "+workspaceColumns+" FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id WHERE w.is_personal AND w.created_by = $1 AND m.user_id = $1",
+		userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // User has no personal workspace
+		}
+		return nil, fmt.Errorf("failed to get personal workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// ListWorkspacesTx retrieves the workspaces the user is a member of within a transaction,
+// the personal workspace first.
+func (pdb *PostgresDB) ListWorkspacesTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]*models.Workspace, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+workspaceColumns+" FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id WHERE m.user_id = $1 ORDER BY w.is_personal DESC, lower(w.name), w.id",
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	workspaces := []*models.Workspace{}
+	for rows.Next() {
+		workspace, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workspace row: %w", err)
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return workspaces, nil
+}
+
+// UpdateWorkspaceTx renames a workspace within a transaction.
+// Callers check that the user may manage the workspace.
+func (pdb *PostgresDB) UpdateWorkspaceTx(ctx context.Context, tx *sql.Tx, workspace *models.Workspace) error {
+	err := tx.QueryRowContext(ctx,
+		"UPDATE workspaces SET name = $1, updated_at = NOW() WHERE id = $2 RETURNING is_personal, created_by, created_at, updated_at",
+		workspace.Name, workspace.ID).Scan(&workspace.IsPersonal, &workspace.CreatedBy, &workspace.CreatedAt, &workspace.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to update workspace: %w", err)
+	}
+	return nil
+}
+
+// DeleteWorkspaceTx deletes a workspace within a transaction.
+// Its tasks, projects, labels, members and invitations cascade.
+func (pdb *PostgresDB) DeleteWorkspaceTx(ctx context.Context, tx *sql.Tx, id int32) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM workspaces WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetWorkspaceRoleTx returns the user's role in a workspace within a transaction,
+// or an empty string when the user is not a member.
+// With forUpdate the membership row is locked until the transaction ends.
+func (pdb *PostgresDB) GetWorkspaceRoleTx(ctx context.Context, tx *sql.Tx, workspaceID int32, userID uuid.UUID, forUpdate bool) (string, error) {
+	query := "SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	var role string
+	if err := tx.QueryRowContext(ctx, query, workspaceID, userID).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil // Not a member
+		}
+		return "", fmt.Errorf("failed to get workspace role: %w", err)
+	}
+	return role, nil
+}
+
+// AddWorkspaceMemberTx adds a user to a workspace within a transaction.
+// It reports false, leaving the membership untouched, when the user already is a member.
+func (pdb *PostgresDB) AddWorkspaceMemberTx(ctx context.Context, tx *sql.Tx, workspaceID int32, userID uuid.UUID, role string) (bool, error) {
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+		workspaceID, userID, role)
+	if err != nil {
+		return false, fmt.Errorf("failed to add workspace member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListWorkspaceMembersTx retrieves the members of a workspace within a transaction, owners first.
+func (pdb *PostgresDB) ListWorkspaceMembersTx(ctx context.Context, tx *sql.Tx, workspaceID int32) ([]*models.WorkspaceMember, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT m.workspace_id, m.user_id, u.email, u.display_name, m.role, m.created_at
+		FROM workspace_members m JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY array_position(ARRAY['owner', 'admin', 'member', 'viewer']::varchar[], m.role), lower(u.display_name), m.user_id`,
+		workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*models.WorkspaceMember{}
+	for rows.Next() {
+		member := &models.WorkspaceMember{}
+		if err := rows.Scan(&member.WorkspaceID, &member.UserID, &member.Email, &member.DisplayName, &member.Role, &member.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace member row: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return members, nil
+}
+
+// SetWorkspaceMemberRoleTx changes the role of a member within a transaction.
+func (pdb *PostgresDB) SetWorkspaceMemberRoleTx(ctx context.Context, tx *sql.Tx, workspaceID int32, userID uuid.UUID, role string) error {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE workspace_members SET role = $1 WHERE workspace_id = $2 AND user_id = $3", role, workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set workspace member role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RemoveWorkspaceMemberTx removes a member from a workspace within a transaction.
+// Tasks the member created stay in the workspace.
+func (pdb *PostgresDB) RemoveWorkspaceMemberTx(ctx context.Context, tx *sql.Tx, workspaceID int32, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2", workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CountWorkspaceOwnersTx returns the number of owners of a workspace within a transaction.
+// The owner rows are locked, so concurrent demotions cannot remove the last owner.
+func (pdb *PostgresDB) CountWorkspaceOwnersTx(ctx context.Context, tx *sql.Tx, workspaceID int32) (int, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT user_id FROM workspace_members WHERE workspace_id = $1 AND role = 'owner' FOR UPDATE", workspaceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count workspace owners: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return count, nil
+}
+
+// IsWorkspaceMemberEmailTx reports whether a user with the given email is a member of the workspace.
+func (pdb *PostgresDB) IsWorkspaceMemberEmailTx(ctx context.Context, tx *sql.Tx, workspaceID int32, email string) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM workspace_members m JOIN users u ON u.id = m.user_id
+			WHERE m.workspace_id = $1 AND lower(u.email) = lower($2))`, workspaceID, email).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check workspace member: %w", err)
+	}
+	return exists, nil
+}
+
+// ReleaseUserWorkspacesTx prepares the deletion of a user within a transaction.
+// The personal workspace and workspaces without other members are deleted, and in
+// workspaces the user solely owns the most privileged longest-standing member becomes owner.
+func (pdb *PostgresDB) ReleaseUserWorkspacesTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM workspaces w
+		WHERE (w.is_personal AND w.created_by = $1)
+			OR (w.id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1)
+				AND NOT EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = w.id AND m.user_id <> $1))`,
+		userID); err != nil {
+		return fmt.Errorf("failed to delete user workspaces: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE workspace_members m SET role = 'owner'
+		FROM (
+			SELECT DISTINCT ON (workspace_id) workspace_id, user_id FROM workspace_members
+			WHERE user_id <> $1
+				AND workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1 AND role = 'owner')
+				AND workspace_id NOT IN (SELECT workspace_id FROM workspace_members WHERE user_id <> $1 AND role = 'owner')
+			ORDER BY workspace_id, array_position(ARRAY['admin', 'member', 'viewer']::varchar[], role), created_at, user_id
+		) heir
+		WHERE m.workspace_id = heir.workspace_id AND m.user_id = heir.user_id`, userID); err != nil {
+		return fmt.Errorf("failed to hand over workspace ownership: %w", err)
+	}
+	return nil
+}
+
+// CreateWorkspaceInvitationTx creates an invitation within a transaction.
+// An open invitation for the same email is renewed with the new role and expiry instead.
+func (pdb *PostgresDB) CreateWorkspaceInvitationTx(ctx context.Context, tx *sql.Tx, inv *models.WorkspaceInvitation) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO workspace_invitations (workspace_id, email, role, invited_by, expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (workspace_id, lower(email)) WHERE accepted_at IS NULL AND declined_at IS NULL
+		DO UPDATE SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by, expires_at = EXCLUDED.expires_at, created_at = NOW()
+		RETURNING id, created_at`,
+		inv.WorkspaceID, inv.Email, inv.Role, inv.InvitedBy, inv.ExpiresAt).Scan(&inv.ID, &inv.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace invitation: %w", err)
+	}
+	return nil
+}
+
+// ListWorkspaceInvitationsTx retrieves the open invitations of a workspace within a transaction, newest first.
+// Expired invitations are included until they are revoked or renewed.
+func (pdb *PostgresDB) ListWorkspaceInvitationsTx(ctx context.Context, tx *sql.Tx, workspaceID int32) ([]*models.WorkspaceInvitation, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+invitationColumns+` FROM workspace_invitations i JOIN workspaces w ON w.id = i.workspace_id
+		WHERE i.workspace_id = $1 AND i.accepted_at IS NULL AND i.declined_at IS NULL
+		ORDER BY i.created_at DESC, i.id DESC`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace invitations: %w", err)
+	}
+	defer rows.Close()
+
+	return collectInvitations(rows)
+}
+
+// DeleteWorkspaceInvitationTx revokes an open invitation of a workspace within a transaction.
+func (pdb *PostgresDB) DeleteWorkspaceInvitationTx(ctx context.Context, tx *sql.Tx, workspaceID, id int32) error {
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM workspace_invitations WHERE id = $1 AND workspace_id = $2 AND accepted_at IS NULL AND declined_at IS NULL",
+		id, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListInvitationsForEmailTx retrieves the open, unexpired invitations sent to an email within a transaction.
+func (pdb *PostgresDB) ListInvitationsForEmailTx(ctx context.Context, tx *sql.Tx, email string) ([]*models.WorkspaceInvitation, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+invitationColumns+` FROM workspace_invitations i JOIN workspaces w ON w.id = i.workspace_id
+		WHERE lower(i.email) = lower($1) AND i.accepted_at IS NULL AND i.declined_at IS NULL AND i.expires_at > NOW()
+		ORDER BY i.created_at DESC, i.id DESC`, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer rows.Close()
+
+	return collectInvitations(rows)
+}
+
+// GetInvitationForEmailTx retrieves and locks an open, unexpired invitation sent to an email within a transaction.
+func (pdb *PostgresDB) GetInvitationForEmailTx(ctx context.Context, tx *sql.Tx, id int32, email string) (*models.WorkspaceInvitation, error) {
+	inv, err := scanInvitation(tx.QueryRowContext(ctx, `
+		SELECT `+invitationColumns+` FROM workspace_invitations i JOIN workspaces w ON w.id = i.workspace_id
+		WHERE i.id = $1 AND lower(i.email) = lower($2) AND i.accepted_at IS NULL AND i.declined_at IS NULL AND i.expires_at > NOW()
+		FOR UPDATE OF i`, id, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Invitation not found
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return inv, nil
+}
+
+// AnswerInvitationTx marks an invitation accepted or declined within a transaction.
+func (pdb *PostgresDB) AnswerInvitationTx(ctx context.Context, tx *sql.Tx, inv *models.WorkspaceInvitation, accept bool) error {
+	err := tx.QueryRowContext(ctx, `
+		UPDATE workspace_invitations SET
+			accepted_at = CASE WHEN $1 THEN NOW() END,
+			declined_at = CASE WHEN NOT $1 THEN NOW() END
+		WHERE id = $2 RETURNING accepted_at, declined_at`, accept, inv.ID).Scan(&inv.AcceptedAt, &inv.DeclinedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to answer invitation: %w", err)
+	}
+	return nil
+}
+
+// collectInvitations scans all rows selected with invitationColumns.
+func collectInvitations(rows *sql.Rows) ([]*models.WorkspaceInvitation, error) {
+	invitations := []*models.WorkspaceInvitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation row: %w", err)
+		}
+		invitations = append(invitations, inv)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return invitations, nil
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ArchiveProjectHandler handles POST requests to archive a project.
+func ArchiveProjectHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return setProjectArchivedHandler(tm, true)
+}
+
+// UnarchiveProjectHandler handles POST requests to restore an archived project.
+func UnarchiveProjectHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return setProjectArchivedHandler(tm, false)
+}
+
+func setProjectArchivedHandler(tm *app.TaskManagerService, archived bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.ParseInt(idStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+
+		project, err := tm.SetProjectArchived(r.Context(), int32(id), userID, archived)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				http.Error(w, "Project not found", http.StatusNotFound)
+			case errors.Is(err, models.ErrInvalidInput):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, models.ErrForbidden):
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				http.Error(w, fmt.Sprintf("Failed to archive project: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(project)
+	}
+}
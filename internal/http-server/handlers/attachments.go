@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// UploadRequest is the body of a request to start a resumable upload.
+type UploadRequest struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+// uploadAttachmentHandler handles multipart POST requests attaching the file in the "file" field to a task.
+// The file is streamed, so the field should come last when the form has others.
+func UploadAttachmentHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		reader, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, "Expected a multipart/form-data body", http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				http.Error(w, "Missing file field", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Invalid multipart body", http.StatusBadRequest)
+				return
+			}
+			if part.FormName() != "file" {
+				part.Close()
+				continue
+			}
+
+			attachment, err := tm.UploadAttachment(r.Context(), int32(taskID), userID, part.FileName(), part)
+			part.Close()
+			if err != nil {
+				writeAttachmentError(w, err, "upload attachment")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(attachment)
+			return
+		}
+	}
+}
+
+// listAttachmentsHandler handles GET requests for the attachments of a task.
+func ListAttachmentsHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		attachments, err := tm.ListAttachments(r.Context(), int32(taskID), userID)
+		if err != nil {
+			writeAttachmentError(w, err, "list attachments")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(attachments)
+	}
+}
+
+// getAttachmentHandler handles GET requests for the metadata of an attachment.
+func GetAttachmentHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, attachmentID, ok := attachmentParams(w, r)
+		if !ok {
+			return
+		}
+
+		attachment, err := tm.GetAttachment(r.Context(), taskID, attachmentID, userID)
+		if err != nil {
+			writeAttachmentError(w, err, "get attachment")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(attachment)
+	}
+}
+
+// downloadAttachmentHandler handles GET requests for the contents of an attachment.
+// Range and conditional requests are supported. The file is always served as a download
+// with its sniffed content type, so it cannot be rendered as a page of the API's origin.
+func DownloadAttachmentHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, attachmentID, ok := attachmentParams(w, r)
+		if !ok {
+			return
+		}
+
+		attachment, content, err := tm.OpenAttachment(r.Context(), taskID, attachmentID, userID)
+		if err != nil {
+			writeAttachmentError(w, err, "download attachment")
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", attachment.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		// Blobs never change, so their key identifies the contents.
+		w.Header().Set("ETag", `"`+path.Base(attachment.BlobKey)+`"`)
+		http.ServeContent(w, r, attachment.Filename, attachment.CreatedAt, content)
+	}
+}
+
+// deleteAttachmentHandler handles DELETE requests for an attachment.
+func DeleteAttachmentHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, attachmentID, ok := attachmentParams(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tm.DeleteAttachment(r.Context(), taskID, attachmentID, userID); err != nil {
+			writeAttachmentError(w, err, "delete attachment")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// createUploadHandler handles POST requests starting a resumable upload, with a body like
+// {"filename": "scan.pdf", "size": 73400320}. The chunks are then sent to the returned upload.
+func CreateUploadHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		var req UploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		upload, err := tm.CreateUpload(r.Context(), int32(taskID), userID, req.Filename, req.Size)
+		if err != nil {
+			writeAttachmentError(w, err, "create upload")
+			return
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/tasks/%d/attachments/uploads/%s", taskID, upload.ID))
+		writeUpload(w, upload, http.StatusCreated)
+	}
+}
+
+// getUploadHandler handles GET and HEAD requests for a resumable upload. The Upload-Offset header
+// tells where the next chunk has to start.
+func GetUploadHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, uploadID, ok := uploadParams(w, r)
+		if !ok {
+			return
+		}
+
+		upload, err := tm.GetUpload(r.Context(), taskID, uploadID, userID)
+		if err != nil {
+			writeAttachmentError(w, err, "get upload")
+			return
+		}
+
+		writeUpload(w, upload, http.StatusOK)
+	}
+}
+
+// appendUploadHandler handles PATCH requests sending the next chunk of a resumable upload as the raw body.
+// The Upload-Offset header must match the offset of the upload, otherwise 409 is returned.
+// It responds with 204 and the new offset, or with 201 and the attachment once the last chunk arrived.
+func AppendUploadHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, uploadID, ok := uploadParams(w, r)
+		if !ok {
+			return
+		}
+
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			http.Error(w, "Missing or invalid Upload-Offset header", http.StatusBadRequest)
+			return
+		}
+
+		upload, attachment, err := tm.AppendUploadChunk(r.Context(), taskID, uploadID, userID, offset, r.Body)
+		if err != nil {
+			writeAttachmentError(w, err, "append to upload")
+			return
+		}
+
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		if attachment == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(attachment)
+	}
+}
+
+// abortUploadHandler handles DELETE requests cancelling a resumable upload.
+func AbortUploadHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, uploadID, ok := uploadParams(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tm.AbortUpload(r.Context(), taskID, uploadID, userID); err != nil {
+			writeAttachmentError(w, err, "abort upload")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// attachmentParams parses the task and attachment IDs of the URL, responding with 400 when invalid.
+func attachmentParams(w http.ResponseWriter, r *http.Request) (int32, int32, bool) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	attachmentID, err := strconv.ParseInt(chi.URLParam(r, "attachmentID"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return int32(taskID), int32(attachmentID), true
+}
+
+// uploadParams parses the task and upload IDs of the URL, responding with 400 when invalid.
+func uploadParams(w http.ResponseWriter, r *http.Request) (int32, uuid.UUID, bool) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return 0, uuid.Nil, false
+	}
+	uploadID, err := uuid.Parse(chi.URLParam(r, "uploadID"))
+	if err != nil {
+		http.Error(w, "Invalid upload ID", http.StatusBadRequest)
+		return 0, uuid.Nil, false
+	}
+	return int32(taskID), uploadID, true
+}
+
+// writeUpload writes an upload along with its offset and length headers.
+func writeUpload(w http.ResponseWriter, upload *models.AttachmentUpload, status int) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(upload)
+}
+
+// writeAttachmentError maps errors of the attachment operations to HTTP responses.
+func writeAttachmentError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrPreconditionFailed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrTooLarge), errors.Is(err, models.ErrQuotaExceeded):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, models.ErrUnsupportedType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// CommentRequest is the body of a request to post or edit a comment.
+type CommentRequest struct {
+	Body string `json:"body"`
+}
+
+// createCommentHandler handles POST requests to comment on a task, with a Markdown body like
+// {"body": "Looks good, @jane@example.com please review"}.
+func CreateCommentHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		var req CommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		comment := &models.TaskComment{TaskID: int32(taskID), Body: req.Body}
+		if err := tm.CreateComment(r.Context(), comment, userID); err != nil {
+			writeCommentError(w, err, "create comment")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(comment)
+	}
+}
+
+// listCommentsHandler handles GET requests for the comments of a task.
+func ListCommentsHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		comments, err := tm.ListComments(r.Context(), int32(taskID), userID)
+		if err != nil {
+			writeCommentError(w, err, "list comments")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(comments)
+	}
+}
+
+// updateCommentHandler handles PATCH requests to edit the body of the user's comment.
+func UpdateCommentHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, commentID, ok := commentIDParams(w, r)
+		if !ok {
+			return
+		}
+
+		var req CommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		comment := &models.TaskComment{ID: commentID, TaskID: taskID, Body: req.Body}
+		if err := tm.UpdateComment(r.Context(), comment, userID); err != nil {
+			writeCommentError(w, err, "update comment")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(comment)
+	}
+}
+
+// deleteCommentHandler handles DELETE requests for a comment.
+func DeleteCommentHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, commentID, ok := commentIDParams(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tm.DeleteComment(r.Context(), taskID, commentID, userID); err != nil {
+			writeCommentError(w, err, "delete comment")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// listCommentHistoryHandler handles GET requests for the previous bodies of an edited comment.
+func ListCommentHistoryHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, commentID, ok := commentIDParams(w, r)
+		if !ok {
+			return
+		}
+
+		revisions, err := tm.ListCommentHistory(r.Context(), taskID, commentID, userID)
+		if err != nil {
+			writeCommentError(w, err, "list comment history")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(revisions)
+	}
+}
+
+// getTaskActivityHandler handles GET requests for the activity feed of a task,
+// its comments interleaved with status and due date changes.
+func GetTaskActivityHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		items, err := tm.GetTaskActivity(r.Context(), int32(taskID), userID)
+		if err != nil {
+			writeCommentError(w, err, "get task activity")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(items)
+	}
+}
+
+// commentIDParams parses the task and comment IDs of the URL, writing a 400 response when one is invalid.
+func commentIDParams(w http.ResponseWriter, r *http.Request) (int32, int32, bool) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return int32(taskID), int32(commentID), true
+}
+
+// writeCommentError maps the errors of comment operations to responses.
+func writeCommentError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// createLabelHandler handles POST requests to create a new label.
+func CreateLabelHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var label models.Label
+		if err := json.NewDecoder(r.Body).Decode(&label); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.CreateLabel(r.Context(), &label, userID); err != nil {
+			switch {
+			case errors.Is(err, models.ErrInvalidInput):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, models.ErrAlreadyExists):
+				http.Error(w, "Label with this name already exists", http.StatusConflict)
+			case errors.Is(err, models.ErrForbidden):
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				http.Error(w, fmt.Sprintf("Failed to create label: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(label)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -28,24 +29,23 @@ func CreateTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
 
 		id, err := tm.CreateTask(r.Context(), &task, userID)
 		if err != nil {
+			if errors.Is(err, models.ErrInvalidInput) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, models.ErrForbidden) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
 			http.Error(w, fmt.Sprintf("Failed to create task: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		task.ID = id
 		task.UserID = userID
+		w.Header().Set("ETag", taskETag(&task))
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(task)
 	}
 }
-
-// *** Leave this function for later ***
-// func hasScope(scopes []string, requiredScope string) bool {
-// 	for _, scope := range scopes {
-// 		if scope == requiredScope {
-// 			return true
-// 		}
-// 	}
-// 	return false
-// }
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// deleteLabelHandler handles DELETE requests to delete a label.
+func DeleteLabelHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.ParseInt(idStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid label ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.DeleteLabel(r.Context(), int32(id), userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Label not found", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, models.ErrForbidden) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to delete label: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// deleteProjectHandler handles DELETE requests to delete a project.
+// ?mode=move (default) moves its tasks to the Inbox, ?mode=cascade deletes them.
+func DeleteProjectHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.ParseInt(idStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.DeleteProject(r.Context(), int32(id), userID, r.URL.Query().Get("mode")); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				http.Error(w, "Project not found", http.StatusNotFound)
+			case errors.Is(err, models.ErrInvalidInput):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, models.ErrForbidden):
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				http.Error(w, fmt.Sprintf("Failed to delete project: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -9,11 +9,13 @@ import (
 
 	"github.com/HellUpa/taskmanager/internal/app"
 	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
-// deleteTaskHandler handles DELETE requests to delete a task.
+// deleteTaskHandler handles DELETE requests to delete a task, which moves it to the trash.
+// With an If-Match header the task is only deleted while its ETag matches, otherwise 412 is returned.
 func DeleteTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
@@ -29,11 +31,19 @@ func DeleteTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
 			return
 		}
 
-		if err := tm.DeleteTask(r.Context(), int32(id), userID); err != nil {
+		if err := tm.DeleteTask(r.Context(), int32(id), userID, parseIfMatch(r)); err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				http.Error(w, "Task not found", http.StatusNotFound)
 				return
 			}
+			if errors.Is(err, models.ErrPreconditionFailed) {
+				http.Error(w, "Task has been modified", http.StatusPreconditionFailed)
+				return
+			}
+			if errors.Is(err, models.ErrForbidden) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
 			http.Error(w, fmt.Sprintf("Failed to delete task: %v", err), http.StatusInternalServerError)
 			return
 		}
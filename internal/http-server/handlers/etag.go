@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HellUpa/taskmanager/internal/models"
+)
+
+// taskETag returns the strong entity tag of a task: its version followed by a digest of its
+// representation, which also covers the labels, subtask progress and recurrence rule that
+// change without a new version, e.g. "7-1f2e3d4c5b6a7988".
+func taskETag(task *models.Task) string {
+	body, _ := json.Marshal(task)
+	sum := sha256.Sum256(body)
+	return `"` + strconv.FormatInt(int64(task.Version), 10) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// parseIfMatch returns the task versions listed in the If-Match header. Only the version part
+// of a tag is compared, as that is what guards concurrent writes to the task.
+// It returns nil when the header is absent or "*", so no version check is applied.
+// Weak or foreign tags never match, a header made only of those yields an empty slice.
+func parseIfMatch(r *http.Request) []int32 {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := []int32{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") || len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		v, err := strconv.ParseInt(version, 10, 32)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, int32(v))
+	}
+	return versions
+}
+
+// ifNoneMatch reports whether the If-None-Match header matches etag using weak comparison.
+func ifNoneMatch(r *http.Request, etag string) bool {
+	header := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// getLabelHandler handles GET requests to retrieve a label by ID.
+func GetLabelHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.ParseInt(idStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid label ID", http.StatusBadRequest)
+			return
+		}
+
+		label, err := tm.GetLabel(r.Context(), int32(id), userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get label: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if label == nil {
+			http.Error(w, "Label not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(label)
+	}
+}
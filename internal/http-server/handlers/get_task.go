@@ -13,6 +13,7 @@ import (
 )
 
 // getTaskHandler handles GET requests to retrieve a task by ID.
+// The response carries an ETag, a matching If-None-Match header yields 304 Not Modified.
 func GetTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
@@ -38,6 +39,13 @@ func GetTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
 			http.Error(w, "Task not found", http.StatusNotFound)
 			return
 		}
+
+		etag := taskETag(task)
+		w.Header().Set("ETag", etag)
+		if ifNoneMatch(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(task)
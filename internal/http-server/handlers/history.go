@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// getTaskHistoryHandler handles GET requests for the audit history of a task: who changed
+// which fields from what to what, in which request, oldest first.
+func GetTaskHistoryHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		events, err := tm.GetTaskHistory(r.Context(), int32(taskID), userID)
+		if err != nil {
+			writeHistoryError(w, err, "get task history")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(events)
+	}
+}
+
+// restoreTaskRevisionHandler handles POST requests restoring a task to the version in the URL.
+// It accepts an If-Match header like the PUT handler and responds with the stored task.
+func RestoreTaskRevisionHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		version, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+
+		task, err := tm.RestoreTaskRevision(r.Context(), int32(taskID), int32(version), userID, parseIfMatch(r))
+		if err != nil {
+			writeHistoryError(w, err, "restore task")
+			return
+		}
+
+		w.Header().Set("ETag", taskETag(task))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(task)
+	}
+}
+
+// writeHistoryError maps errors of the history operations to HTTP responses.
+func writeHistoryError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrPreconditionFailed):
+		http.Error(w, "Task has been modified", http.StatusPreconditionFailed)
+	case errors.Is(err, models.ErrInvalidTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), http.StatusInternalServerError)
+	}
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
 	"github.com/HellUpa/taskmanager/internal/models"
 
 	"github.com/google/uuid"
@@ -15,32 +18,28 @@ import (
 // This structure needs to match the structure of the webhook payload sent by Kratos.
 // Refer to the Kratos documentation for the exact structure.
 type KratosWebhookPayload struct {
-	ID string `json:"userId"`
+	ID     string                `json:"userId"`
+	Traits models.IdentityTraits `json:"traits"`
 }
 
 // KratosRegistrationWebhookHandler handles webhooks from Kratos after user registration.
 func KratosRegistrationWebhookHandler(tm *app.TaskManagerService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// 1. Verify the request (check Authorization header, if you set one up)
-		//    (Omitted for brevity in this example, but VERY important in production)
-
-		var payload KratosWebhookPayload
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		// The sender is verified by WebhookAuthMiddleware.
+		payload, ok := decodeKratosWebhook(w, r)
+		if !ok {
 			return
 		}
-
 		kratosID := payload.ID
-		if kratosID == "" {
-			http.Error(w, "Kratos ID missing in webhook payload", http.StatusBadRequest)
-			return
-		}
 
 		newUser := &models.User{
 			ID:       uuid.New(),
 			KratosID: kratosID,
+			Email:    payload.Traits.Email,
 		}
 
+		// The user is created together with their default Inbox project.
+		// Retries for an existing identity succeed without creating anything.
 		if err := tm.CreateUser(r.Context(), newUser); err != nil {
 			http.Error(w, fmt.Sprintf("Failed to create user: %v", err), http.StatusInternalServerError)
 			return
@@ -49,3 +48,67 @@ func KratosRegistrationWebhookHandler(tm *app.TaskManagerService) http.HandlerFu
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// KratosSettingsWebhookHandler handles webhooks from Kratos after a settings flow,
+// copying changed identity traits onto the user.
+func KratosSettingsWebhookHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, ok := decodeKratosWebhook(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tm.SyncUserTraits(r.Context(), payload.ID, payload.Traits); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to sync user traits: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// KratosIdentityDeletedWebhookHandler handles webhooks sent when an identity is deleted in Kratos,
+// deleting the user and all of their data. Unknown identities are accepted, so retries succeed.
+func KratosIdentityDeletedWebhookHandler(tm *app.TaskManagerService, cache *middlewares.SessionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, ok := decodeKratosWebhook(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tm.DeleteUserByKratosID(r.Context(), payload.ID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("Failed to delete user: %v", err), http.StatusInternalServerError)
+			return
+		}
+		cache.InvalidateIdentity(payload.ID)
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// decodeKratosWebhook decodes a webhook payload and checks it names a Kratos identity,
+// writing an error response otherwise.
+func decodeKratosWebhook(w http.ResponseWriter, r *http.Request) (KratosWebhookPayload, bool) {
+	var payload KratosWebhookPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return payload, false
+	}
+
+	if payload.ID == "" {
+		http.Error(w, "Kratos ID missing in webhook payload", http.StatusBadRequest)
+		return payload, false
+	}
+	// Kratos identity IDs are UUIDs, store them in their canonical form.
+	id, err := uuid.Parse(payload.ID)
+	if err != nil {
+		http.Error(w, "Kratos ID in webhook payload is not a valid UUID", http.StatusBadRequest)
+		return payload, false
+	}
+	payload.ID = id.String()
+	return payload, true
+}
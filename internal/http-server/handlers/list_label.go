@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/google/uuid"
+)
+
+// listLabelsHandler handles GET requests to list the labels of the user's workspaces.
+// The list is restricted to one workspace with ?workspace_id=.
+func ListLabelsHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		workspaceID, err := workspaceIDQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		labels, err := tm.ListLabels(r.Context(), userID, workspaceID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list labels: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(labels)
+	}
+}
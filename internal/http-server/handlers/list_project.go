@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/google/uuid"
+)
+
+// listProjectsHandler handles GET requests to list the projects of the user's workspaces.
+// Archived projects are included with ?archived=true, ?workspace_id= restricts the list to one workspace.
+func ListProjectsHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		includeArchived := false
+		if v := r.URL.Query().Get("archived"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "Invalid archived value", http.StatusBadRequest)
+				return
+			}
+			includeArchived = b
+		}
+
+		workspaceID, err := workspaceIDQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		projects, err := tm.ListProjects(r.Context(), userID, workspaceID, includeArchived)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list projects: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(projects)
+	}
+}
@@ -2,15 +2,26 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/HellUpa/taskmanager/internal/app"
 	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
-// listTasksHandler handles GET requests to list all tasks.
+// listTasksHandler handles GET requests to list tasks.
+// Supported query parameters: workspace_id, project_id, completed, status (repeatable), priority (repeatable),
+// due (today|overdue|none), tz (IANA zone for due, defaults to the user's), due_before, due_after,
+// created_before, created_after, updated_before, updated_after, label (repeatable), sort,
+// order (asc|desc), limit, cursor and include_deleted (also returns tasks in the trash, with their
+// deleted_at set, so that clients syncing with updated_after see deletions).
 func ListTasksHandler(tm *app.TaskManagerService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID)
@@ -21,13 +32,180 @@ func ListTasksHandler(tm *app.TaskManagerService) http.HandlerFunc {
 
 		tm.Log.Debug("List task handler for", "userID", userID)
 
-		tasks, err := tm.ListTasks(r.Context(), userID)
+		opts, err := parseTaskListOptions(r.URL.Query())
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to list tasks: %v", err), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := tm.ListTasks(r.Context(), userID, opts)
+		if err != nil {
+			writeListTasksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+// listProjectTasksHandler handles GET requests to list the tasks of a project.
+// It accepts the same query parameters as ListTasksHandler.
+func ListProjectTasksHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+
+		opts, err := parseTaskListOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pid := int32(projectID)
+		opts.ProjectID = &pid
+
+		project, err := tm.GetProject(r.Context(), pid, userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get project: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if project == nil {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+
+		page, err := tm.ListTasks(r.Context(), userID, opts)
+		if err != nil {
+			writeListTasksError(w, err)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(tasks)
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+// parseTaskListOptions builds list options from the request query string.
+func parseTaskListOptions(q url.Values) (*models.TaskListOptions, error) {
+	opts := &models.TaskListOptions{
+		SortBy:     q.Get("sort"),
+		Labels:     q["label"],
+		Statuses:   q["status"],
+		Priorities: q["priority"],
+		Due:        q.Get("due"),
+	}
+
+	if v := q.Get("tz"); v != "" {
+		loc, err := models.LoadTimeZone(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tz value %q", v)
+		}
+		opts.Location = loc
+	}
+
+	if v := q.Get("workspace_id"); v != "" {
+		workspaceID, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace_id value %q", v)
+		}
+		wid := int32(workspaceID)
+		opts.WorkspaceID = &wid
+	}
+
+	if v := q.Get("project_id"); v != "" {
+		projectID, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project_id value %q", v)
+		}
+		pid := int32(projectID)
+		opts.ProjectID = &pid
+	}
+
+	if v := q.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid completed value %q", v)
+		}
+		opts.Completed = &completed
+	}
+
+	if v := q.Get("include_deleted"); v != "" {
+		includeDeleted, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include_deleted value %q", v)
+		}
+		opts.IncludeDeleted = includeDeleted
+	}
+
+	timeParams := []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"due_before", &opts.DueBefore},
+		{"due_after", &opts.DueAfter},
+		{"created_before", &opts.CreatedBefore},
+		{"created_after", &opts.CreatedAfter},
+		{"updated_before", &opts.UpdatedBefore},
+		{"updated_after", &opts.UpdatedAfter},
+	}
+	for _, p := range timeParams {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q, expected RFC 3339 timestamp", p.name, v)
+		}
+		*p.dst = &t
+	}
+
+	switch order := q.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.SortDesc = true
+	default:
+		return nil, fmt.Errorf("invalid order value %q, expected asc or desc", order)
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit value %q", v)
+		}
+		opts.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := models.DecodeTaskCursor(v)
+		if err != nil {
+			return nil, err
+		}
+		opts.Cursor = cursor
+	}
+
+	if err := opts.Normalize(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// writeListTasksError maps errors of task listings to HTTP responses. A cursor that decodes but
+// does not fit the listing, e.g. one whose values were tampered with, is a client error.
+func writeListTasksError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to list tasks: %v", err), http.StatusInternalServerError)
 	}
 }
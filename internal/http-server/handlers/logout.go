@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	kratos "github.com/ory/kratos-client-go"
+)
+
+// logoutHandler handles POST requests ending the current Kratos session. Session tokens of native
+// apps are revoked through the API logout, browser sessions through a logout flow whose cookie
+// changes are passed on to the browser. The cached verification of the session is dropped,
+// so the session stops working at once.
+func LogoutHandler(tm *app.TaskManagerService, kratosClient *kratos.APIClient, cache *middlewares.SessionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resp *http.Response
+		var err error
+		if token, ok := middlewares.SessionToken(r); ok {
+			body := kratos.NewPerformNativeLogoutBody(token)
+			resp, err = kratosClient.FrontendAPI.PerformNativeLogout(r.Context()).PerformNativeLogoutBody(*body).Execute()
+		} else if cookie, cookieErr := r.Cookie(middlewares.SessionCookieName); cookieErr == nil {
+			var flow *kratos.LogoutFlow
+			flow, resp, err = kratosClient.FrontendAPI.CreateBrowserLogoutFlow(r.Context()).Cookie(cookie.String()).Execute()
+			if err == nil {
+				resp.Body.Close()
+				resp, err = kratosClient.FrontendAPI.UpdateLogoutFlow(r.Context()).Token(flow.LogoutToken).Cookie(cookie.String()).Execute()
+			}
+		} else {
+			http.Error(w, "No session to log out", http.StatusBadRequest)
+			return
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		// A session Kratos no longer knows is logged out already.
+		if err != nil && (resp == nil || resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+			tm.Log.Error("Failed to log out Kratos session", "error", err)
+			http.Error(w, "Failed to log out", http.StatusBadGateway)
+			return
+		}
+		cache.InvalidateRequest(r)
+
+		if err == nil {
+			for _, cookie := range resp.Header.Values("Set-Cookie") {
+				w.Header().Add("Set-Cookie", cookie)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+	kratos "github.com/ory/kratos-client-go"
+)
+
+// UpdateProfileRequest holds the editable profile fields.
+type UpdateProfileRequest struct {
+	DisplayName string `json:"display_name"`
+}
+
+// getMeHandler handles GET requests for the profile of the current user.
+func GetMeHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := tm.GetUserByID(r.Context(), userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get profile: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// updateMeHandler handles PUT requests to update the profile of the current user.
+// The email is managed by Kratos and cannot be changed here.
+func UpdateMeHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req UpdateProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		user, err := tm.UpdateProfile(r.Context(), userID, req.DisplayName)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidInput) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to update profile: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// getSettingsHandler handles GET requests for the settings of the current user.
+func GetSettingsHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		settings, err := tm.GetSettings(r.Context(), userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(settings)
+	}
+}
+
+// updateSettingsHandler handles PUT requests that replace the settings of the current user.
+func UpdateSettingsHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var settings models.UserSettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.UpdateSettings(r.Context(), userID, &settings); err != nil {
+			if errors.Is(err, models.ErrInvalidInput) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, models.ErrForbidden) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to update settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(settings)
+	}
+}
+
+// exportMeHandler handles GET requests for a download of all data of the current user.
+func ExportMeHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		export, err := tm.ExportUserData(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to export data: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("taskmanager-export-%s.json", export.ExportedAt.Format("2006-01-02"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(export)
+	}
+}
+
+// deleteMeHandler handles DELETE requests to delete the current user and all of their data.
+// When kratosAdmin is set, the Kratos identity is deleted first, so the account cannot be used
+// afterwards; otherwise only the local data is deleted.
+func DeleteMeHandler(tm *app.TaskManagerService, kratosAdmin *kratos.APIClient, cache *middlewares.SessionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := tm.GetUserByID(r.Context(), userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get user: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if kratosAdmin != nil {
+			resp, err := kratosAdmin.IdentityAPI.DeleteIdentity(r.Context(), user.KratosID).Execute()
+			if resp != nil {
+				resp.Body.Close()
+			}
+			// An identity that is already gone is what we want.
+			if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+				tm.Log.Error("Failed to delete Kratos identity", "kratos_id", user.KratosID, "error", err)
+				http.Error(w, "Failed to delete identity", http.StatusBadGateway)
+				return
+			}
+		}
+
+		if err := tm.DeleteUser(r.Context(), userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to delete user: %v", err), http.StatusInternalServerError)
+			return
+		}
+		cache.InvalidateIdentity(user.KratosID)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
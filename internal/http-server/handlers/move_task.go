@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// MoveTaskRequest is the body of a request to move a task between projects.
+// A null project_id takes the task out of any project.
+type MoveTaskRequest struct {
+	ProjectID *int32 `json:"project_id"`
+}
+
+// moveTaskHandler handles POST requests to move a task into another project.
+func MoveTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.ParseInt(idStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		var req MoveTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.MoveTask(r.Context(), int32(id), req.ProjectID, userID); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				http.Error(w, "Task not found", http.StatusNotFound)
+			case errors.Is(err, models.ErrInvalidInput):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, models.ErrForbidden):
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				http.Error(w, fmt.Sprintf("Failed to move task: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		task, err := tm.GetTask(r.Context(), int32(id), userID)
+		if err != nil || task == nil {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(task)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const defaultOccurrencePreview = 5
+
+// previewOccurrencesHandler handles GET requests to preview the next due dates of a recurring task.
+// The number of dates is set with ?count=N.
+func PreviewOccurrencesHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.ParseInt(idStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		count := defaultOccurrencePreview
+		if v := r.URL.Query().Get("count"); v != "" {
+			if count, err = strconv.Atoi(v); err != nil {
+				http.Error(w, "Invalid count", http.StatusBadRequest)
+				return
+			}
+		}
+
+		occurrences, err := tm.PreviewOccurrences(r.Context(), int32(id), userID, count)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				http.Error(w, "Task not found", http.StatusNotFound)
+			case errors.Is(err, models.ErrInvalidInput):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, fmt.Sprintf("Failed to preview occurrences: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(occurrences)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxPatchBodySize limits the size of a merge patch document.
+const maxPatchBodySize = 1 << 20
+
+// patchTaskHandler handles PATCH requests that partially update a task with an RFC 7396
+// JSON merge patch (Content-Type application/merge-patch+json or application/json).
+// It accepts the same query parameters and If-Match header as the PUT handler and responds
+// with the stored task.
+func PatchTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.ParseInt(idStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			w.Header().Set("Accept-Patch", "application/merge-patch+json")
+			http.Error(w, "Unsupported patch format, use application/merge-patch+json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBodySize))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		opts := models.TaskUpdateOptions{Scope: r.URL.Query().Get("scope"), IfMatch: parseIfMatch(r)}
+		if v := r.URL.Query().Get("complete_subtasks"); v != "" {
+			completeSubtasks, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "Invalid complete_subtasks value", http.StatusBadRequest)
+				return
+			}
+			opts.CompleteSubtasks = completeSubtasks
+		}
+
+		task, err := tm.PatchTask(r.Context(), int32(id), userID, patch, opts)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Task not found", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, models.ErrInvalidInput) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, models.ErrPreconditionFailed) {
+				http.Error(w, "Task has been modified", http.StatusPreconditionFailed)
+				return
+			}
+			if errors.Is(err, models.ErrInvalidTransition) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if errors.Is(err, models.ErrForbidden) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to patch task: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", taskETag(task))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(task)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/google/uuid"
+)
+
+// searchTasksHandler handles GET requests to search tasks by title and description.
+func SearchTasksHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		if query == "" {
+			http.Error(w, "Search query is required", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if v := r.URL.Query().Get("limit"); v != "" {
+			l, err := strconv.Atoi(v)
+			if err != nil || l <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = l
+		}
+
+		results, err := tm.SearchTasks(r.Context(), userID, query, limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to search tasks: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(results)
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// SetTaskAssigneeRequest is the body of a request to change the assignee of a task.
+// A null assignee_id unassigns the task.
+type SetTaskAssigneeRequest struct {
+	AssigneeID *uuid.UUID `json:"assignee_id"`
+}
+
+// listAssignedTasksHandler handles GET requests to list the tasks assigned to the user, in any workspace.
+// It accepts the same query parameters as ListTasksHandler.
+func ListAssignedTasksHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return listRelatedTasksHandler(tm, models.TaskRelationAssigned)
+}
+
+// listSharedTasksHandler handles GET requests to list the tasks other users shared with the user.
+// It accepts the same query parameters as ListTasksHandler.
+func ListSharedTasksHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return listRelatedTasksHandler(tm, models.TaskRelationShared)
+}
+
+// listRelatedTasksHandler lists the tasks reaching the user through the given relation.
+func listRelatedTasksHandler(tm *app.TaskManagerService, relation string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		opts, err := parseTaskListOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Relation = relation
+
+		page, err := tm.ListTasks(r.Context(), userID, opts)
+		if err != nil {
+			writeListTasksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+// setTaskAssigneeHandler handles PUT requests to assign a task to a user or unassign it.
+// It responds with the updated task, or 204 when the user can no longer see it.
+func SetTaskAssigneeHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		var req SetTaskAssigneeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.SetTaskAssignee(r.Context(), int32(id), req.AssigneeID, userID); err != nil {
+			writeTaskShareError(w, err, "set task assignee")
+			return
+		}
+
+		task, err := tm.GetTask(r.Context(), int32(id), userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if task == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(task)
+	}
+}
+
+// listTaskSharesHandler handles GET requests for the users a task is shared with.
+func ListTaskSharesHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		shares, err := tm.ListTaskShares(r.Context(), int32(id), userID)
+		if err != nil {
+			writeTaskShareError(w, err, "list task shares")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(shares)
+	}
+}
+
+// shareTaskHandler handles POST requests to share a task with a user,
+// with a body like {"email": "jane@example.com", "permission": "edit"}.
+func ShareTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		var share models.TaskShare
+		if err := json.NewDecoder(r.Body).Decode(&share); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		share.TaskID = int32(id)
+
+		if err := tm.ShareTask(r.Context(), &share, userID); err != nil {
+			writeTaskShareError(w, err, "share task")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(share)
+	}
+}
+
+// unshareTaskHandler handles DELETE requests to revoke the share of a task with a user.
+// Users leave a shared task by revoking their own share.
+func UnshareTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+		memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.UnshareTask(r.Context(), int32(id), memberID, userID); err != nil {
+			writeTaskShareError(w, err, "unshare task")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeTaskShareError maps the errors of assignment and sharing operations to responses.
+func writeTaskShareError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// attachTaskLabelHandler handles PUT requests to attach a label to a task.
+func AttachTaskLabelHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, labelID, ok := parseTaskLabelIDs(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tm.AttachTaskLabel(r.Context(), taskID, labelID, userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Task or label not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to attach label: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// detachTaskLabelHandler handles DELETE requests to detach a label from a task.
+func DetachTaskLabelHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		taskID, labelID, ok := parseTaskLabelIDs(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tm.DetachTaskLabel(r.Context(), taskID, labelID, userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Label is not attached to the task", http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to detach label: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// parseTaskLabelIDs reads the task and label IDs from the URL, writing a 400 response on failure.
+func parseTaskLabelIDs(w http.ResponseWriter, r *http.Request) (int32, int32, bool) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	labelID, err := strconv.ParseInt(chi.URLParam(r, "labelID"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid label ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return int32(taskID), int32(labelID), true
+}
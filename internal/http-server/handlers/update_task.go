@@ -16,6 +16,10 @@ import (
 )
 
 // updateTaskHandler handles PUT requests to update an existing task.
+// With ?complete_subtasks=true completing the task also completes all of its descendants.
+// For recurring tasks ?scope=series applies title and description to the whole series,
+// the default scope=this only changes this occurrence. An If-Match header makes the update
+// conditional on the task's ETag, a mismatch is answered with 412 Precondition Failed.
 func UpdateTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
@@ -40,16 +44,44 @@ func UpdateTaskHandler(tm *app.TaskManagerService) http.HandlerFunc {
 		task.ID = int32(id)
 		task.UserID = userID
 
-		if err := tm.UpdateTask(r.Context(), &task); err != nil {
+		opts := models.TaskUpdateOptions{Scope: r.URL.Query().Get("scope"), IfMatch: parseIfMatch(r)}
+		if v := r.URL.Query().Get("complete_subtasks"); v != "" {
+			completeSubtasks, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "Invalid complete_subtasks value", http.StatusBadRequest)
+				return
+			}
+			opts.CompleteSubtasks = completeSubtasks
+		}
+
+		updated, err := tm.UpdateTask(r.Context(), &task, opts)
+		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				http.Error(w, "Task not found", http.StatusNotFound)
 				return
 			}
+			if errors.Is(err, models.ErrInvalidInput) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, models.ErrPreconditionFailed) {
+				http.Error(w, "Task has been modified", http.StatusPreconditionFailed)
+				return
+			}
+			if errors.Is(err, models.ErrInvalidTransition) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if errors.Is(err, models.ErrForbidden) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
 			http.Error(w, fmt.Sprintf("Failed to update task: %v", err), http.StatusInternalServerError)
 			return
 		}
+		w.Header().Set("ETag", taskETag(updated))
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(task)
+		json.NewEncoder(w).Encode(updated)
 	}
 }
@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	middlewares "github.com/HellUpa/taskmanager/internal/http-server/middleware"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// createWorkspaceHandler handles POST requests to create a shared workspace owned by the user.
+func CreateWorkspaceHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var workspace models.Workspace
+		if err := json.NewDecoder(r.Body).Decode(&workspace); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.CreateWorkspace(r.Context(), &workspace, userID); err != nil {
+			writeWorkspaceError(w, err, "create workspace")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(workspace)
+	}
+}
+
+// listWorkspacesHandler handles GET requests for the workspaces the user is a member of.
+func ListWorkspacesHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		workspaces, err := tm.ListWorkspaces(r.Context(), userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list workspaces: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(workspaces)
+	}
+}
+
+// getWorkspaceHandler handles GET requests for a single workspace of the user.
+func GetWorkspaceHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		workspace, err := tm.GetWorkspace(r.Context(), id, userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get workspace: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if workspace == nil {
+			http.Error(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(workspace)
+	}
+}
+
+// updateWorkspaceHandler handles PUT requests to rename a workspace.
+func UpdateWorkspaceHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		var workspace models.Workspace
+		if err := json.NewDecoder(r.Body).Decode(&workspace); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		workspace.ID = id
+
+		if err := tm.UpdateWorkspace(r.Context(), &workspace, userID); err != nil {
+			writeWorkspaceError(w, err, "update workspace")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(workspace)
+	}
+}
+
+// deleteWorkspaceHandler handles DELETE requests to delete a shared workspace with all of its content.
+func DeleteWorkspaceHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tm.DeleteWorkspace(r.Context(), id, userID); err != nil {
+			writeWorkspaceError(w, err, "delete workspace")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// listWorkspaceMembersHandler handles GET requests for the members of a workspace.
+func ListWorkspaceMembersHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		members, err := tm.ListWorkspaceMembers(r.Context(), id, userID)
+		if err != nil {
+			writeWorkspaceError(w, err, "list workspace members")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(members)
+	}
+}
+
+// updateWorkspaceMemberHandler handles PUT requests to change the role of a member,
+// with a body like {"role": "admin"}.
+func UpdateWorkspaceMemberHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+		memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.UpdateWorkspaceMemberRole(r.Context(), id, memberID, req.Role, userID); err != nil {
+			writeWorkspaceError(w, err, "update workspace member")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// removeWorkspaceMemberHandler handles DELETE requests to remove a member from a workspace.
+// Members leave a workspace by removing themselves.
+func RemoveWorkspaceMemberHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+		memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.RemoveWorkspaceMember(r.Context(), id, memberID, userID); err != nil {
+			writeWorkspaceError(w, err, "remove workspace member")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// createWorkspaceInvitationHandler handles POST requests to invite an email address to a workspace,
+// with a body like {"email": "jane@example.com", "role": "member"}.
+func CreateWorkspaceInvitationHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		var inv models.WorkspaceInvitation
+		if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		inv.WorkspaceID = id
+
+		if err := tm.InviteToWorkspace(r.Context(), &inv, userID); err != nil {
+			writeWorkspaceError(w, err, "invite to workspace")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(inv)
+	}
+}
+
+// listWorkspaceInvitationsHandler handles GET requests for the open invitations of a workspace.
+func ListWorkspaceInvitationsHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		invitations, err := tm.ListWorkspaceInvitations(r.Context(), id, userID)
+		if err != nil {
+			writeWorkspaceError(w, err, "list workspace invitations")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(invitations)
+	}
+}
+
+// revokeWorkspaceInvitationHandler handles DELETE requests to revoke an open invitation.
+func RevokeWorkspaceInvitationHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, ok := workspaceIDParam(w, r)
+		if !ok {
+			return
+		}
+		invitationID, err := strconv.ParseInt(chi.URLParam(r, "invitationID"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := tm.RevokeWorkspaceInvitation(r.Context(), id, int32(invitationID), userID); err != nil {
+			writeWorkspaceError(w, err, "revoke workspace invitation")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// listMyInvitationsHandler handles GET requests for the open invitations sent to the user's email.
+func ListMyInvitationsHandler(tm *app.TaskManagerService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		invitations, err := tm.ListMyInvitations(r.Context(), userID)
+		if err != nil {
+			writeWorkspaceError(w, err, "list invitations")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(invitations)
+	}
+}
+
+// answerInvitationHandler handles POST requests to accept or decline an invitation sent to the user.
+// Accepting responds with the joined workspace, declining with 204.
+func AnswerInvitationHandler(tm *app.TaskManagerService, accept bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(uuid.UUID) // Get user ID from context
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+			return
+		}
+
+		workspace, err := tm.AnswerInvitation(r.Context(), int32(id), userID, accept)
+		if err != nil {
+			writeWorkspaceError(w, err, "answer invitation")
+			return
+		}
+		if !accept {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(workspace)
+	}
+}
+
+// workspaceIDParam parses the workspace ID of the URL, writing a 400 response when it is invalid.
+func workspaceIDParam(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// workspaceIDQuery parses the optional workspace_id query parameter that restricts lists to one workspace.
+func workspaceIDQuery(r *http.Request) (*int32, error) {
+	v := r.URL.Query().Get("workspace_id")
+	if v == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace_id value %q", v)
+	}
+	wid := int32(id)
+	return &wid, nil
+}
+
+// writeWorkspaceError maps the errors of workspace operations to responses.
+func writeWorkspaceError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrAlreadyExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), http.StatusInternalServerError)
+	}
+}
@@ -2,48 +2,144 @@ package middlewares
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/HellUpa/taskmanager/internal/app"
+	"github.com/HellUpa/taskmanager/internal/config"
+	"github.com/HellUpa/taskmanager/internal/models"
 
 	kratos "github.com/ory/kratos-client-go"
 )
 
 type contextKey string
 
+// SessionCookieName is the name of the Kratos session cookie of browsers.
+const SessionCookieName = "ory_kratos_session"
+
 const (
 	UserIDKey contextKey = "userID"
-	//ScopesKey contextKey = "scopes"
+	// ScopesKey holds the scopes granted to the request's credentials.
+	// It is absent for browser sessions, which are not restricted.
+	ScopesKey contextKey = "scopes"
 )
 
-// AuthMiddleware creates a middleware that authenticates requests using Kratos sessions.
-func AuthMiddleware(kratosClient *kratos.APIClient, tm *app.TaskManagerService, ui_ip string) func(http.Handler) http.Handler {
+// AuthMiddleware creates a middleware that authenticates requests using Kratos sessions,
+// or personal access tokens and OAuth2 access tokens sent as "Authorization: Bearer <token>".
+// OAuth2 access tokens are validated with Hydra when an introspector is configured.
+// Kratos sessions are read from the session cookie of browsers, or from the session token
+// native apps send as "X-Session-Token" or "Authorization: Bearer".
+// Verified sessions are kept in the session cache, which may be nil.
+// Users missing for a valid session are provisioned on the fly unless disabled in cfg.
+func AuthMiddleware(kratosClient *kratos.APIClient, introspector *TokenIntrospector, tm *app.TaskManagerService, cache *SessionCache, cfg config.AuthConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get the session cookie. The cookie name is set by Kratos.
-			cookie, err := r.Cookie("ory_kratos_session")
-			if err != nil {
-				tm.Log.Info("Unauthorized: no session cookie, redirect to login")
-				http.Redirect(w, r, fmt.Sprintf("http://%v:4433/self-service/login/browser", ui_ip), http.StatusSeeOther)
+			if secret, ok := bearerToken(r); ok && strings.HasPrefix(secret, models.PersonalAccessTokenPrefix) {
+				token, err := tm.AuthenticateToken(r.Context(), secret)
+				if err != nil {
+					tm.Log.Error("Failed to authenticate personal access token", "error", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+				if token == nil {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					unauthorized(w, r, "invalid or expired token")
+					return
+				}
+				tm.Log.Debug("Personal access token accepted", "user_id", token.UserID, "token_id", token.ID)
+				ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+				ctx = context.WithValue(ctx, ScopesKey, token.GrantedScopes())
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			if token, ok := bearerToken(r); ok && introspector != nil && strings.HasPrefix(token, OAuth2AccessTokenPrefix) {
+				result, err := introspector.Introspect(r.Context(), token)
+				if err != nil {
+					tm.Log.Error("Failed to introspect OAuth2 access token", "error", err)
+					http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				if !result.Active || result.Subject == "" {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					unauthorized(w, r, "invalid or expired access token")
+					return
+				}
+				// The subject of tokens issued through the Kratos login is the Kratos identity ID.
+				user, err := tm.GetUserByKratosID(r.Context(), result.Subject)
+				if err != nil {
+					tm.Log.Error("Failed to get user from db by Kratos ID", "error", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+				if user == nil {
+					tm.Log.Warn("OAuth2 access token subject is not a known user", "subject", result.Subject, "client_id", result.ClientID)
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					unauthorized(w, r, "access token is not issued for a user")
+					return
+				}
+				tm.Log.Debug("OAuth2 access token accepted", "user_id", user.ID, "client_id", result.ClientID)
+				ctx := context.WithValue(r.Context(), UserIDKey, user.ID)
+				ctx = context.WithValue(ctx, ScopesKey, result.Scopes())
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			var cacheKey string
+			req := kratosClient.FrontendAPI.ToSession(r.Context())
+			if token, ok := SessionToken(r); ok {
+				// Native apps use the session token issued by Kratos API flows.
+				req = req.XSessionToken(token)
+				cacheKey = sessionCacheKey("token", token)
+			} else {
+				// Get the session cookie. The cookie name is set by Kratos.
+				cookie, err := r.Cookie(SessionCookieName)
+				if err != nil {
+					if !acceptsHTML(r) {
+						tm.Log.Info("Unauthorized: no session credentials")
+						unauthorized(w, r, "missing session credentials")
+						return
+					}
+					tm.Log.Info("Unauthorized: no session cookie, redirect to login")
+					http.Redirect(w, r, fmt.Sprintf("http://%v:4433/self-service/login/browser", cfg.UI_IP), http.StatusSeeOther)
+					return
+				}
+				req = req.Cookie(cookie.String())
+				cacheKey = sessionCacheKey("cookie", cookie.Value)
+			}
+
+			if entry, ok := cache.get(r.Context(), cacheKey); ok {
+				if !entry.valid {
+					unauthorized(w, r, "invalid or expired session")
+					return
+				}
+				ctx := context.WithValue(r.Context(), UserIDKey, entry.userID)
+				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
 
 			// Verify the session with Kratos.
-			session, resp, err := kratosClient.FrontendAPI.ToSession(r.Context()).Cookie(cookie.String()).Execute()
+			session, resp, err := req.Execute()
 			if err != nil {
 				tm.Log.Warn("Kratos session verification failed", "error", err)
 				if resp != nil {
 					tm.Log.Warn("Kratos session response", "http_status", resp.StatusCode)
+					// Only remember sessions Kratos rejected, not failures to reach it.
+					if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+						cache.addInvalid(cacheKey)
+					}
 				}
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				unauthorized(w, r, "invalid or expired session")
 				return
 			}
 			defer resp.Body.Close()
 
 			if !session.GetActive() {
 				tm.Log.Warn("Kratos session is inactive")
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				cache.addInvalid(cacheKey)
+				unauthorized(w, r, "inactive session")
 				return
 			}
 
@@ -58,10 +154,33 @@ func AuthMiddleware(kratosClient *kratos.APIClient, tm *app.TaskManagerService,
 			}
 
 			if user == nil {
-				tm.Log.Error("Failed to find user by Kratos ID, after success auth", "error", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				return
+				if !cfg.JITProvisioning {
+					tm.Log.Error("Failed to find user by Kratos ID, after success auth", "kratos_id", kratosID)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+				// The registration webhook was missed, create the user now.
+				traits, _ := identityTraits(session.Identity)
+				var created bool
+				user, created, err = tm.EnsureUser(r.Context(), kratosID, traits)
+				if err != nil {
+					tm.Log.Error("Failed to provision user for Kratos ID", "kratos_id", kratosID, "error", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+				if created {
+					tm.Log.Info("User provisioned on first request", "user_id", user.ID, "kratos_id", kratosID)
+				}
+			}
+			// Keep the profile in sync with identity traits changed outside of our webhooks.
+			if traits, ok := identityTraits(session.Identity); ok && traits.Email != user.Email {
+				if err := tm.SyncUserTraits(r.Context(), kratosID, traits); err != nil {
+					tm.Log.Warn("Failed to sync user traits", "error", err)
+				}
 			}
+
+			cache.addValid(cacheKey, kratosID, user.ID, session.ExpiresAt)
+
 			tm.Log.Debug("User found by Kratos ID, write in ctx", "user_id", user.ID)
 			// Store the user ID in the context.
 			ctx := context.WithValue(r.Context(), UserIDKey, user.ID)
@@ -73,3 +192,58 @@ func AuthMiddleware(kratosClient *kratos.APIClient, tm *app.TaskManagerService,
 		})
 	}
 }
+
+// identityTraits extracts the traits copied into the user profile from a Kratos identity.
+func identityTraits(identity *kratos.Identity) (models.IdentityTraits, bool) {
+	if identity == nil {
+		return models.IdentityTraits{}, false
+	}
+	return models.ParseIdentityTraits(identity.Traits)
+}
+
+// bearerToken returns the credentials of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// SessionToken returns the Kratos session token of a non-browser client, sent as
+// "X-Session-Token" or as an "Authorization: Bearer" header that is not another kind of token.
+func SessionToken(r *http.Request) (string, bool) {
+	if token := strings.TrimSpace(r.Header.Get("X-Session-Token")); token != "" {
+		return token, true
+	}
+	if token, ok := bearerToken(r); ok && !strings.HasPrefix(token, models.PersonalAccessTokenPrefix) && !strings.HasPrefix(token, OAuth2AccessTokenPrefix) {
+		return token, true
+	}
+	return "", false
+}
+
+// acceptsHTML reports whether the client asks for HTML, i.e. is a browser that can follow the login redirect.
+func acceptsHTML(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), "text/html") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unauthorized writes a 401 response, as JSON unless the client asks for HTML.
+func unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	if acceptsHTML(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized", "message": message})
+}
@@ -0,0 +1,196 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/HellUpa/taskmanager/internal/config"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+	kratos "github.com/ory/kratos-client-go"
+)
+
+const (
+	testKratosID     = "9f1c2d3e-0000-4000-8000-000000000001"
+	testSessionToken = "ory_st_valid"
+	testCookie       = "valid-cookie"
+)
+
+// newFakeKratos starts a stand-in for the Kratos whoami endpoint that accepts testSessionToken
+// and testCookie, and records the credentials it was called with.
+func newFakeKratos(t *testing.T) (*kratos.APIClient, *[]*http.Request) {
+	t.Helper()
+	var calls []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sessions/whoami" {
+			t.Errorf("unexpected Kratos request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		calls = append(calls, r)
+
+		valid := r.Header.Get("X-Session-Token") == testSessionToken
+		if cookie, err := r.Cookie("ory_kratos_session"); err == nil && cookie.Value == testCookie {
+			valid = true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":401,"status":"Unauthorized","message":"No valid session credentials found in the request."}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":     "5b7c9e1a-0000-4000-8000-000000000002",
+			"active": true,
+			"identity": map[string]any{
+				"id":         testKratosID,
+				"schema_id":  "default",
+				"schema_url": "http://kratos/schemas/default",
+				"traits":     map[string]any{"email": "jane@example.com"},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := kratos.NewConfiguration()
+	cfg.Servers = kratos.ServerConfigurations{{URL: srv.URL}}
+	return kratos.NewAPIClient(cfg), &calls
+}
+
+// serveAuth sends a request through AuthMiddleware and returns the response and the user ID
+// the next handler saw, uuid.Nil when it was not reached.
+func serveAuth(t *testing.T, r *http.Request) (*httptest.ResponseRecorder, uuid.UUID, *[]*http.Request) {
+	t.Helper()
+	client, calls := newFakeKratos(t)
+	user := &models.User{ID: uuid.New(), KratosID: testKratosID, Email: "jane@example.com", TimeZone: "UTC"}
+	tm := newTestService(t, user)
+
+	var seen uuid.UUID
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Context().Value(UserIDKey).(uuid.UUID)
+		if _, scoped := r.Context().Value(ScopesKey).([]string); scoped {
+			t.Error("session requests must not carry scopes")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	AuthMiddleware(client, nil, tm, nil, config.AuthConfig{UI_IP: "ui.example"})(next).ServeHTTP(w, r)
+	if seen != uuid.Nil && seen != user.ID {
+		t.Errorf("user ID = %s, want %s", seen, user.ID)
+	}
+	return w, seen, calls
+}
+
+func TestAuthMiddlewareSessionTokenHeader(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	r.Header.Set("X-Session-Token", testSessionToken)
+
+	w, seen, calls := serveAuth(t, r)
+	if w.Code != http.StatusNoContent || seen == uuid.Nil {
+		t.Fatalf("status = %d, want the request to pass", w.Code)
+	}
+	if len(*calls) != 1 || (*calls)[0].Header.Get("X-Session-Token") != testSessionToken {
+		t.Errorf("Kratos was not asked about the session token")
+	}
+}
+
+func TestAuthMiddlewareBearerSessionToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	r.Header.Set("Authorization", "Bearer "+testSessionToken)
+
+	w, seen, calls := serveAuth(t, r)
+	if w.Code != http.StatusNoContent || seen == uuid.Nil {
+		t.Fatalf("status = %d, want the request to pass", w.Code)
+	}
+	if len(*calls) != 1 || (*calls)[0].Header.Get("X-Session-Token") != testSessionToken {
+		t.Errorf("Bearer session token was not forwarded to Kratos as X-Session-Token")
+	}
+}
+
+func TestAuthMiddlewareCookieFallback(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	r.AddCookie(&http.Cookie{Name: "ory_kratos_session", Value: testCookie})
+
+	w, seen, calls := serveAuth(t, r)
+	if w.Code != http.StatusNoContent || seen == uuid.Nil {
+		t.Fatalf("status = %d, want the request to pass", w.Code)
+	}
+	if len(*calls) != 1 || (*calls)[0].Header.Get("X-Session-Token") != "" {
+		t.Errorf("cookie session must be verified with the cookie only")
+	}
+}
+
+func TestAuthMiddlewareSessionTokenTakesPrecedenceOverCookie(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	r.Header.Set("X-Session-Token", "ory_st_revoked")
+	r.AddCookie(&http.Cookie{Name: "ory_kratos_session", Value: testCookie})
+
+	w, seen, _ := serveAuth(t, r)
+	if w.Code != http.StatusUnauthorized || seen != uuid.Nil {
+		t.Fatalf("status = %d, want 401 for the rejected session token", w.Code)
+	}
+}
+
+func TestAuthMiddlewareUnauthorizedResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		token    string
+		wantCode int
+		wantJSON bool
+	}{
+		{name: "API client without credentials", accept: "application/json", wantCode: http.StatusUnauthorized, wantJSON: true},
+		{name: "client without Accept header", wantCode: http.StatusUnauthorized, wantJSON: true},
+		{name: "API client with rejected token", accept: "application/json", token: "ory_st_revoked", wantCode: http.StatusUnauthorized, wantJSON: true},
+		{name: "browser with rejected token", accept: "text/html,application/xhtml+xml", token: "ory_st_revoked", wantCode: http.StatusUnauthorized},
+		{name: "browser without credentials", accept: "text/html", wantCode: http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if tt.token != "" {
+				r.Header.Set("X-Session-Token", tt.token)
+			}
+
+			w, seen, _ := serveAuth(t, r)
+			if seen != uuid.Nil {
+				t.Fatal("request reached the next handler")
+			}
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			switch {
+			case tt.wantCode == http.StatusSeeOther:
+				if loc := w.Header().Get("Location"); loc != "http://ui.example:4433/self-service/login/browser" {
+					t.Errorf("Location = %q, want the Kratos login flow", loc)
+				}
+			case tt.wantJSON:
+				if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+				var body map[string]string
+				if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+					t.Fatalf("decode body: %v", err)
+				}
+				if body["error"] != "unauthorized" || body["message"] == "" {
+					t.Errorf("body = %v, want an unauthorized error with a message", body)
+				}
+			default:
+				if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+					t.Errorf("Content-Type = %q, want text/plain", ct)
+				}
+				if body := strings.TrimSpace(w.Body.String()); body != "Unauthorized" {
+					t.Errorf("body = %q, want Unauthorized", body)
+				}
+			}
+		})
+	}
+}
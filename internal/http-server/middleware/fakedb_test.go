@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	"github.com/HellUpa/taskmanager/internal/config"
+	"github.com/HellUpa/taskmanager/internal/db"
+	"github.com/HellUpa/taskmanager/internal/models"
+)
+
+// fakeDBCount numbers the fake databases, each test gets its own driver registration.
+var fakeDBCount atomic.Int64
+
+// newTestService returns a service whose database only knows the given users, looked up by Kratos ID.
+// Any other query fails the test.
+func newTestService(t *testing.T, users ...*models.User) *app.TaskManagerService {
+	t.Helper()
+	name := fmt.Sprintf("fakedb-%d", fakeDBCount.Add(1))
+	sql.Register(name, &fakeDriver{t: t, users: users})
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("open fake database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return app.NewTaskManagerService(log, &db.PostgresDB{DB: sqlDB}, config.TasksConfig{}, nil, config.AttachmentsConfig{})
+}
+
+// fakeDriver is a database/sql driver answering the user lookups of the auth middleware.
+type fakeDriver struct {
+	t     *testing.T
+	users []*models.User
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.t.Errorf("unexpected statement: %s", s.query)
+	return nil, fmt.Errorf("unexpected statement")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.Contains(s.query, "FROM users WHERE kratos_id = $1") {
+		s.d.t.Errorf("unexpected query: %s", s.query)
+		return nil, fmt.Errorf("unexpected query")
+	}
+	rows := &fakeRows{}
+	for _, u := range s.d.users {
+		if u.KratosID == args[0] {
+			rows.values = append(rows.values, []driver.Value{u.ID.String(), u.KratosID, u.Email, u.DisplayName, u.TimeZone, time.Time{}, time.Time{}})
+		}
+	}
+	return rows, nil
+}
+
+type fakeRows struct{ values [][]driver.Value }
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "kratos_id", "email", "display_name", "time_zone", "created_at", "updated_at"}
+}
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
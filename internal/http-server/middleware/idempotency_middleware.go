@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/HellUpa/taskmanager/internal/app"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client chosen key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotencyKeyLength matches the key column of the idempotency_keys table.
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize limits the request body hashed to detect reused keys.
+	maxIdempotentBodySize = 1 << 20
+)
+
+// replayedHeaders are the response headers stored with an idempotent response.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyMiddleware makes a route safe to retry when the client sends an Idempotency-Key header.
+// The first response for a key is stored per user and replayed for later requests with the same key
+// and body. A duplicate arriving while the first request is still running gets 409 Conflict, a key
+// reused with a different body 422. Server errors are not stored so that the request can be retried.
+// It must run after AuthMiddleware.
+func IdempotencyMiddleware(tm *app.TaskManagerService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			userID, ok := r.Context().Value(UserIDKey).(uuid.UUID)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			stored, err := tm.BeginIdempotentRequest(r.Context(), userID, key, requestHash(r, body))
+			if err != nil {
+				switch {
+				case errors.Is(err, models.ErrIdempotencyKeyInUse):
+					http.Error(w, err.Error(), http.StatusConflict)
+				case errors.Is(err, models.ErrIdempotencyKeyMismatch):
+					http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				default:
+					tm.Log.Error("Failed to claim idempotency key", "error", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+			if stored != nil {
+				for name, value := range stored.ResponseHeaders {
+					w.Header().Set(name, value)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(*stored.StatusCode)
+				w.Write(stored.ResponseBody)
+				return
+			}
+
+			// The outcome is stored even if the client goes away or the request times out.
+			ctx := context.WithoutCancel(r.Context())
+			completed := false
+			defer func() {
+				if !completed {
+					if err := tm.ReleaseIdempotencyKey(ctx, userID, key); err != nil {
+						tm.Log.Error("Failed to release idempotency key", "error", err)
+					}
+				}
+			}()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var buf bytes.Buffer
+			ww.Tee(&buf)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				return
+			}
+
+			rec := &models.IdempotencyRecord{
+				UserID:          userID,
+				Key:             key,
+				StatusCode:      &status,
+				ResponseHeaders: make(map[string]string),
+				ResponseBody:    buf.Bytes(),
+			}
+			for _, name := range replayedHeaders {
+				if value := ww.Header().Get(name); value != "" {
+					rec.ResponseHeaders[name] = value
+				}
+			}
+			if err := tm.CompleteIdempotentRequest(ctx, rec); err != nil {
+				tm.Log.Error("Failed to store idempotent response", "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// requestHash fingerprints the method, path and body of a request.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
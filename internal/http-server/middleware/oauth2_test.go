@@ -0,0 +1,161 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/config"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// newFakeHydra starts a stand-in for the Hydra introspection endpoint answering with the
+// response registered for each token; unknown tokens are inactive.
+func newFakeHydra(t *testing.T, tokens map[string]map[string]any) *TokenIntrospector {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/admin/oauth2/introspect" {
+			t.Errorf("unexpected Hydra request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("Content-Type = %q, want a form", ct)
+		}
+		response, ok := tokens[r.PostFormValue("token")]
+		if !ok {
+			response = map[string]any{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(srv.Close)
+	return NewTokenIntrospector(srv.URL+"/", time.Second)
+}
+
+func TestTokenIntrospectorIntrospect(t *testing.T) {
+	future := time.Now().Add(time.Hour).Unix()
+	introspector := newFakeHydra(t, map[string]map[string]any{
+		"ory_at_active":  {"active": true, "sub": testKratosID, "client_id": "cli", "scope": "tasks:read tasks:write", "exp": future, "token_use": "access_token"},
+		"ory_at_no_use":  {"active": true, "sub": testKratosID, "scope": "tasks:read"},
+		"ory_at_revoked": {"active": false},
+		"ory_at_expired": {"active": true, "sub": testKratosID, "exp": time.Now().Add(-time.Minute).Unix(), "token_use": "access_token"},
+		"ory_rt_refresh": {"active": true, "sub": testKratosID, "exp": future, "token_use": "refresh_token"},
+	})
+
+	tests := []struct {
+		token      string
+		wantActive bool
+		wantScopes []string
+	}{
+		{token: "ory_at_active", wantActive: true, wantScopes: []string{"tasks:read", "tasks:write"}},
+		{token: "ory_at_no_use", wantActive: true, wantScopes: []string{"tasks:read"}},
+		{token: "ory_at_revoked"},
+		{token: "ory_at_unknown"},
+		{token: "ory_at_expired"},
+		{token: "ory_rt_refresh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			result, err := introspector.Introspect(context.Background(), tt.token)
+			if err != nil {
+				t.Fatalf("Introspect: %v", err)
+			}
+			if result.Active != tt.wantActive {
+				t.Errorf("Active = %v, want %v", result.Active, tt.wantActive)
+			}
+			if tt.wantActive && !slices.Equal(result.Scopes(), tt.wantScopes) {
+				t.Errorf("Scopes = %v, want %v", result.Scopes(), tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestTokenIntrospectorHydraFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if _, err := NewTokenIntrospector(srv.URL, time.Second).Introspect(context.Background(), "ory_at_x"); err == nil {
+		t.Fatal("Introspect succeeded, want an error for a failing Hydra")
+	}
+}
+
+func TestNewTokenIntrospectorDisabled(t *testing.T) {
+	if NewTokenIntrospector("", time.Second) != nil {
+		t.Fatal("introspector without admin URL must be nil")
+	}
+}
+
+// TestOAuth2AccessTokens sends access tokens through AuthMiddleware and RequireScopes.
+func TestOAuth2AccessTokens(t *testing.T) {
+	future := time.Now().Add(time.Hour).Unix()
+	introspector := newFakeHydra(t, map[string]map[string]any{
+		"ory_at_reader":   {"active": true, "sub": testKratosID, "scope": "openid tasks:read", "exp": future, "token_use": "access_token"},
+		"ory_at_writer":   {"active": true, "sub": testKratosID, "scope": "tasks:read tasks:write", "exp": future, "token_use": "access_token"},
+		"ory_at_stranger": {"active": true, "sub": "client-credentials-subject", "scope": "tasks:read tasks:write", "exp": future, "token_use": "access_token"},
+		"ory_at_expired":  {"active": true, "sub": testKratosID, "scope": "tasks:read", "exp": time.Now().Add(-time.Minute).Unix(), "token_use": "access_token"},
+		"ory_at_idtoken":  {"active": true, "sub": testKratosID, "scope": "tasks:read", "exp": future, "token_use": "id_token"},
+	})
+	kratosClient, kratosCalls := newFakeKratos(t)
+	user := &models.User{ID: uuid.New(), KratosID: testKratosID, Email: "jane@example.com", TimeZone: "UTC"}
+	tm := newTestService(t, user)
+
+	handler := AuthMiddleware(kratosClient, introspector, tm, nil, config.AuthConfig{})(
+		RequireScopes(models.ScopeTasksRead, models.ScopeTasksWrite)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Context().Value(UserIDKey).(uuid.UUID); got != user.ID {
+					t.Errorf("user ID = %s, want %s", got, user.ID)
+				}
+				if _, ok := r.Context().Value(ScopesKey).([]string); !ok {
+					t.Error("access token requests must carry their scopes")
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})))
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		wantCode int
+	}{
+		{name: "read with read scope", method: http.MethodGet, token: "ory_at_reader", wantCode: http.StatusNoContent},
+		{name: "write with write scope", method: http.MethodPost, token: "ory_at_writer", wantCode: http.StatusNoContent},
+		{name: "write without write scope", method: http.MethodPost, token: "ory_at_reader", wantCode: http.StatusForbidden},
+		{name: "inactive token", method: http.MethodGet, token: "ory_at_unknown", wantCode: http.StatusUnauthorized},
+		{name: "expired token", method: http.MethodGet, token: "ory_at_expired", wantCode: http.StatusUnauthorized},
+		{name: "wrong token use", method: http.MethodGet, token: "ory_at_idtoken", wantCode: http.StatusUnauthorized},
+		{name: "unknown subject", method: http.MethodGet, token: "ory_at_stranger", wantCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/tasks", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			switch tt.wantCode {
+			case http.StatusUnauthorized:
+				if got := w.Header().Get("WWW-Authenticate"); got != `Bearer error="invalid_token"` {
+					t.Errorf("WWW-Authenticate = %q, want invalid_token", got)
+				}
+			case http.StatusForbidden:
+				if got := w.Header().Get("WWW-Authenticate"); got != `Bearer error="insufficient_scope", scope="tasks:write"` {
+					t.Errorf("WWW-Authenticate = %q, want insufficient_scope for tasks:write", got)
+				}
+			}
+		})
+	}
+	if len(*kratosCalls) != 0 {
+		t.Errorf("access tokens were sent to Kratos %d times", len(*kratosCalls))
+	}
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"net/http"
+)
+
+// RequireScopes creates a middleware that limits scoped credentials to the given scopes:
+// safe methods (GET, HEAD, OPTIONS) need readScope, all others writeScope.
+// Requests without scopes in the context, i.e. browser sessions, are let through.
+func RequireScopes(readScope, writeScope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, scoped := r.Context().Value(ScopesKey).([]string)
+			if scoped {
+				required := writeScope
+				switch r.Method {
+				case http.MethodGet, http.MethodHead, http.MethodOptions:
+					required = readScope
+				}
+				if !hasScope(scopes, required) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+required+`"`)
+					http.Error(w, "Forbidden: missing scope "+required, http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession creates a middleware that rejects scoped credentials such as personal
+// access tokens, for endpoints that must only be used from an interactive session.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, scoped := r.Context().Value(ScopesKey).([]string); scoped {
+			http.Error(w, "Forbidden: this endpoint requires a browser session", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasScope reports whether requiredScope is among the granted scopes.
+func hasScope(scopes []string, requiredScope string) bool {
+	for _, scope := range scopes {
+		if scope == requiredScope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package middlewares
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// sessionCacheEntry is the outcome of a Kratos session verification.
+type sessionCacheEntry struct {
+	key       string
+	kratosID  string
+	userID    uuid.UUID
+	valid     bool
+	expiresAt time.Time
+}
+
+// SessionCache is a bounded in-process LRU cache of Kratos session verifications, keyed by
+// a hash of the session cookie or token. Valid sessions are kept until they expire in Kratos,
+// but at most maxTTL; invalid ones for negativeTTL. Logouts through the API drop their entry,
+// a session revoked in Kratos directly stays valid here until its entry expires. A nil cache caches nothing.
+type SessionCache struct {
+	mu          sync.Mutex
+	size        int
+	maxTTL      time.Duration
+	negativeTTL time.Duration
+	entries     map[string]*list.Element
+	order       *list.List
+	// byIdentity indexes the keys of valid sessions by Kratos ID, for invalidation when the identity is deleted.
+	byIdentity map[string]map[string]struct{}
+	lookups    metric.Int64Counter
+	now        func() time.Time
+}
+
+// NewSessionCache creates a session cache holding up to size entries.
+// It returns nil, disabling the cache, when size or maxTTL is not positive.
+// Lookups are counted on the lookups counter with a "result" attribute of "hit" or "miss".
+func NewSessionCache(size int, maxTTL, negativeTTL time.Duration, lookups metric.Int64Counter) *SessionCache {
+	if size <= 0 || maxTTL <= 0 {
+		return nil
+	}
+	return &SessionCache{
+		size:        size,
+		maxTTL:      maxTTL,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+		byIdentity:  make(map[string]map[string]struct{}),
+		lookups:     lookups,
+		now:         time.Now,
+	}
+}
+
+// sessionCacheKey hashes a session credential, so the cache never holds the credential itself.
+func sessionCacheKey(kind, credential string) string {
+	sum := sha256.Sum256([]byte(kind + ":" + credential))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached verification for a key, dropping it when it has expired.
+func (c *SessionCache) get(ctx context.Context, key string) (sessionCacheEntry, bool) {
+	if c == nil {
+		return sessionCacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*sessionCacheEntry)
+		if c.now().Before(entry.expiresAt) {
+			c.order.MoveToFront(elem)
+			c.record(ctx, "hit")
+			return *entry, true
+		}
+		c.removeLocked(elem)
+	}
+	c.record(ctx, "miss")
+	return sessionCacheEntry{}, false
+}
+
+// addValid caches a verified session of a user until the session expires, but at most maxTTL.
+func (c *SessionCache) addValid(key, kratosID string, userID uuid.UUID, sessionExpiresAt *time.Time) {
+	if c == nil {
+		return
+	}
+	expiresAt := c.now().Add(c.maxTTL)
+	if sessionExpiresAt != nil && sessionExpiresAt.Before(expiresAt) {
+		expiresAt = *sessionExpiresAt
+	}
+	c.add(&sessionCacheEntry{key: key, kratosID: kratosID, userID: userID, valid: true, expiresAt: expiresAt})
+}
+
+// addInvalid caches a rejected session for negativeTTL.
+func (c *SessionCache) addInvalid(key string) {
+	if c == nil || c.negativeTTL <= 0 {
+		return
+	}
+	c.add(&sessionCacheEntry{key: key, expiresAt: c.now().Add(c.negativeTTL)})
+}
+
+// add stores an entry, evicting the least recently used one when the cache is full.
+func (c *SessionCache) add(entry *sessionCacheEntry) {
+	if !c.now().Before(entry.expiresAt) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[entry.key]; ok {
+		c.removeLocked(elem)
+	}
+	for c.order.Len() >= c.size {
+		c.removeLocked(c.order.Back())
+	}
+
+	c.entries[entry.key] = c.order.PushFront(entry)
+	if entry.valid {
+		keys := c.byIdentity[entry.kratosID]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			c.byIdentity[entry.kratosID] = keys
+		}
+		keys[entry.key] = struct{}{}
+	}
+}
+
+// InvalidateIdentity drops all cached sessions of a Kratos identity.
+func (c *SessionCache) InvalidateIdentity(kratosID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.byIdentity[kratosID] {
+		if elem, ok := c.entries[key]; ok {
+			c.removeLocked(elem)
+		}
+	}
+	delete(c.byIdentity, kratosID)
+}
+
+// InvalidateRequest drops the cached verification of the session the request carries,
+// so that the session is verified with Kratos again after a logout.
+func (c *SessionCache) InvalidateRequest(r *http.Request) {
+	if c == nil {
+		return
+	}
+	var key string
+	if token, ok := SessionToken(r); ok {
+		key = sessionCacheKey("token", token)
+	} else if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		key = sessionCacheKey("cookie", cookie.Value)
+	} else {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+}
+
+// removeLocked removes an entry. The caller must hold the lock.
+func (c *SessionCache) removeLocked(elem *list.Element) {
+	entry := c.order.Remove(elem).(*sessionCacheEntry)
+	delete(c.entries, entry.key)
+	if keys, ok := c.byIdentity[entry.kratosID]; ok {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(c.byIdentity, entry.kratosID)
+		}
+	}
+}
+
+// record counts a cache lookup.
+func (c *SessionCache) record(ctx context.Context, result string) {
+	if c.lookups != nil {
+		c.lookups.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+	}
+}
@@ -0,0 +1,195 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/config"
+	"github.com/HellUpa/taskmanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// newTestCache returns a session cache whose clock only moves when the returned function is called.
+func newTestCache(size int, maxTTL, negativeTTL time.Duration) (*SessionCache, func(time.Duration)) {
+	cache := NewSessionCache(size, maxTTL, negativeTTL, nil)
+	now := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	return cache, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSessionCacheExpiry(t *testing.T) {
+	cache, advance := newTestCache(10, time.Minute, 10*time.Second)
+	userID := uuid.New()
+	sessionEnd := cache.now().Add(30 * time.Second)
+	cache.addValid("long", testKratosID, userID, nil)
+	cache.addValid("short", testKratosID, userID, &sessionEnd)
+	cache.addInvalid("rejected")
+
+	for _, key := range []string{"long", "short", "rejected"} {
+		if _, ok := cache.get(context.Background(), key); !ok {
+			t.Fatalf("%s: not cached", key)
+		}
+	}
+	if entry, _ := cache.get(context.Background(), "long"); !entry.valid || entry.userID != userID {
+		t.Errorf("entry = %+v, want a valid session of the user", entry)
+	}
+	if entry, _ := cache.get(context.Background(), "rejected"); entry.valid {
+		t.Error("rejected session cached as valid")
+	}
+
+	advance(11 * time.Second)
+	if _, ok := cache.get(context.Background(), "rejected"); ok {
+		t.Error("rejected session kept past the negative TTL")
+	}
+	advance(20 * time.Second)
+	if _, ok := cache.get(context.Background(), "short"); ok {
+		t.Error("session kept past its expiry in Kratos")
+	}
+	if _, ok := cache.get(context.Background(), "long"); !ok {
+		t.Error("session dropped before the maximum TTL")
+	}
+	advance(30 * time.Second)
+	if _, ok := cache.get(context.Background(), "long"); ok {
+		t.Error("session kept past the maximum TTL")
+	}
+}
+
+func TestSessionCacheSkipsExpiredSessions(t *testing.T) {
+	cache, _ := newTestCache(10, time.Minute, 0)
+	expired := cache.now().Add(-time.Second)
+	cache.addValid("expired", testKratosID, uuid.New(), &expired)
+	cache.addInvalid("rejected")
+	if len(cache.entries) != 0 {
+		t.Errorf("cached %d entries, want expired sessions and disabled negative caching to add none", len(cache.entries))
+	}
+}
+
+func TestSessionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, _ := newTestCache(2, time.Minute, time.Minute)
+	cache.addValid("a", "identity-a", uuid.New(), nil)
+	cache.addValid("b", "identity-b", uuid.New(), nil)
+	cache.get(context.Background(), "a")
+	cache.addValid("c", "identity-c", uuid.New(), nil)
+
+	if _, ok := cache.get(context.Background(), "b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(context.Background(), key); !ok {
+			t.Errorf("%s: evicted, want it kept", key)
+		}
+	}
+	if _, ok := cache.byIdentity["identity-b"]; ok {
+		t.Error("evicted entry is still indexed by its identity")
+	}
+}
+
+func TestSessionCacheInvalidateIdentity(t *testing.T) {
+	cache, _ := newTestCache(10, time.Minute, time.Minute)
+	cache.addValid("laptop", testKratosID, uuid.New(), nil)
+	cache.addValid("phone", testKratosID, uuid.New(), nil)
+	cache.addValid("other", "another-identity", uuid.New(), nil)
+
+	cache.InvalidateIdentity(testKratosID)
+	for _, key := range []string{"laptop", "phone"} {
+		if _, ok := cache.get(context.Background(), key); ok {
+			t.Errorf("%s: still cached after invalidating the identity", key)
+		}
+	}
+	if _, ok := cache.get(context.Background(), "other"); !ok {
+		t.Error("session of another identity was dropped")
+	}
+	if _, ok := cache.byIdentity[testKratosID]; ok {
+		t.Error("invalidated identity is still indexed")
+	}
+}
+
+func TestSessionCacheInvalidateRequest(t *testing.T) {
+	cache, _ := newTestCache(10, time.Minute, time.Minute)
+	cache.addValid(sessionCacheKey("token", testSessionToken), testKratosID, uuid.New(), nil)
+	cache.addValid(sessionCacheKey("cookie", testCookie), testKratosID, uuid.New(), nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	r.Header.Set("Authorization", "Bearer "+testSessionToken)
+	cache.InvalidateRequest(r)
+	if _, ok := cache.get(context.Background(), sessionCacheKey("token", testSessionToken)); ok {
+		t.Error("session token still cached after invalidating its request")
+	}
+	if _, ok := cache.get(context.Background(), sessionCacheKey("cookie", testCookie)); !ok {
+		t.Error("another session of the identity was dropped")
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	r.AddCookie(&http.Cookie{Name: SessionCookieName, Value: testCookie})
+	cache.InvalidateRequest(r)
+	if _, ok := cache.get(context.Background(), sessionCacheKey("cookie", testCookie)); ok {
+		t.Error("session cookie still cached after invalidating its request")
+	}
+}
+
+func TestSessionCacheDisabled(t *testing.T) {
+	if NewSessionCache(0, time.Minute, 0, nil) != nil || NewSessionCache(10, 0, 0, nil) != nil {
+		t.Fatal("cache without size or TTL must be nil")
+	}
+	var cache *SessionCache
+	cache.addValid("key", testKratosID, uuid.New(), nil)
+	cache.addInvalid("key")
+	cache.InvalidateIdentity(testKratosID)
+	cache.InvalidateRequest(httptest.NewRequest(http.MethodGet, "/", nil))
+	if _, ok := cache.get(context.Background(), "key"); ok {
+		t.Error("nil cache returned an entry")
+	}
+}
+
+// TestAuthMiddlewareSessionCache sends requests through AuthMiddleware with a cache and
+// counts how often Kratos is asked.
+func TestAuthMiddlewareSessionCache(t *testing.T) {
+	client, calls := newFakeKratos(t)
+	user := &models.User{ID: uuid.New(), KratosID: testKratosID, Email: "jane@example.com", TimeZone: "UTC"}
+	tm := newTestService(t, user)
+	cache := NewSessionCache(10, time.Minute, time.Minute, nil)
+	handler := AuthMiddleware(client, nil, tm, cache, config.AuthConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Context().Value(UserIDKey).(uuid.UUID); got != user.ID {
+			t.Errorf("user ID = %s, want %s", got, user.ID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		r.Header.Set("X-Session-Token", token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := serve(testSessionToken); code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d, want the request to pass", i, code)
+		}
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("Kratos asked %d times, want once for a cached session", len(*calls))
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := serve("ory_st_revoked"); code != http.StatusUnauthorized {
+			t.Fatalf("rejected request %d: status = %d, want 401", i, code)
+		}
+	}
+	if len(*calls) != 2 {
+		t.Fatalf("Kratos asked %d times, want the rejected session cached too", len(*calls))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	r.Header.Set("X-Session-Token", testSessionToken)
+	cache.InvalidateRequest(r)
+	if code := serve(testSessionToken); code != http.StatusNoContent {
+		t.Fatalf("status = %d after eviction, want the session verified again", code)
+	}
+	if len(*calls) != 3 {
+		t.Errorf("Kratos asked %d times, want the evicted session verified again", len(*calls))
+	}
+}
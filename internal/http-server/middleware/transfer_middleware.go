@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// TransferDeadline creates a middleware for endpoints moving file contents, such as attachment
+// uploads and downloads, which do not fit into the server's read and write timeouts or the
+// request timeout. It moves the read and write deadlines of the connection, and the deadline
+// of the request context, to timeout from now.
+func TransferDeadline(log *slog.Logger, timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(timeout)
+			rc := http.NewResponseController(w)
+			if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				log.Warn("Failed to extend read deadline", "error", err)
+			}
+			if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				log.Warn("Failed to extend write deadline", "error", err)
+			}
+
+			ctx, cancel := context.WithDeadline(r.Context(), deadline)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
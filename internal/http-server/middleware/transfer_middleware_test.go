@@ -0,0 +1,97 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// newSlowServer starts a server with short read and write timeouts whose handler reads the body
+// slowly, then answers after a pause. The chi writer wrapper stands in for the logger middleware.
+func newSlowServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	handler := wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestTimeout)
+			return
+		}
+		time.Sleep(300 * time.Millisecond)
+		if err := r.Context().Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusGatewayTimeout)
+			return
+		}
+		w.Write([]byte("done"))
+	}))
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(middleware.NewWrapResponseWriter(w, r.ProtoMajor), r)
+	}))
+	srv.Config.ReadTimeout = 200 * time.Millisecond
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// slowBody sends its chunks with a pause before each.
+type slowBody struct{ chunks [][]byte }
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if len(b.chunks) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(100 * time.Millisecond)
+	n := copy(p, b.chunks[0])
+	b.chunks = b.chunks[1:]
+	return n, nil
+}
+
+func postSlowly(srv *httptest.Server) (string, error) {
+	body := &slowBody{chunks: [][]byte{[]byte("a"), []byte("b"), []byte("c")}}
+	resp, err := http.Post(srv.URL, "application/octet-stream", body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+func TestTransferDeadline(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := newSlowServer(t, TransferDeadline(log, 5*time.Second))
+	got, err := postSlowly(srv)
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if got != "done" {
+		t.Errorf("response = %q, want the handler to finish", got)
+	}
+}
+
+func TestTransferDeadlineSetsContextDeadline(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var deadline time.Time
+	handler := TransferDeadline(log, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+	}))
+	// Recorders support no deadlines, which the middleware tolerates.
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(nil)))
+	if until := time.Until(deadline); until <= 50*time.Second || until > time.Minute {
+		t.Errorf("context deadline in %v, want about a minute", until)
+	}
+}
+
+// TestServerTimeoutsWithoutTransferDeadline makes sure the slow server does cut off
+// transfers, so that TestTransferDeadline checks the middleware.
+func TestServerTimeoutsWithoutTransferDeadline(t *testing.T) {
+	srv := newSlowServer(t, func(next http.Handler) http.Handler { return next })
+	if got, err := postSlowly(srv); err == nil && got == "done" {
+		t.Fatal("transfer succeeded without the middleware, the server timeouts are not effective")
+	}
+}
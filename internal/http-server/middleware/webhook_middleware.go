@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// WebhookAPIKeyHeader carries the shared API key, as configured for Kratos web hooks.
+	WebhookAPIKeyHeader = "X-Webhook-Key"
+	// WebhookTimestampHeader carries the Unix time a signed webhook was sent at.
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	// WebhookSignatureHeader carries "sha256=<hex HMAC-SHA256 of timestamp + "." + body>".
+	WebhookSignatureHeader = "X-Webhook-Signature"
+
+	maxWebhookBodySize = 1 << 20
+)
+
+// WebhookAuthMiddleware creates a middleware that only lets through webhooks from known senders:
+// requests carrying the shared apiKey, or signed with secret within replayWindow of their timestamp.
+// Each signature is accepted once. When neither apiKey nor secret is set, all webhooks are rejected.
+func WebhookAuthMiddleware(apiKey, secret string, replayWindow time.Duration) func(http.Handler) http.Handler {
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]time.Time) // Accepted signatures, until they leave the replay window
+	)
+
+	// firstUse records a signature, and reports whether it was not seen before.
+	firstUse := func(signature string, now time.Time) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for sig, expiresAt := range seen {
+			if now.After(expiresAt) {
+				delete(seen, sig)
+			}
+		}
+		if _, ok := seen[signature]; ok {
+			return false
+		}
+		seen[signature] = now.Add(2 * replayWindow)
+		return true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey == "" && secret == "" {
+				http.Error(w, "Forbidden: webhook verification is not configured", http.StatusForbidden)
+				return
+			}
+
+			if key := r.Header.Get(WebhookAPIKeyHeader); apiKey != "" && key != "" {
+				if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+					http.Error(w, "Unauthorized: invalid webhook key", http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			signature := r.Header.Get(WebhookSignatureHeader)
+			if secret == "" || signature == "" {
+				http.Error(w, "Unauthorized: webhook credentials missing", http.StatusUnauthorized)
+				return
+			}
+
+			timestamp, err := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+			if err != nil {
+				http.Error(w, "Unauthorized: invalid webhook timestamp", http.StatusUnauthorized)
+				return
+			}
+			now := time.Now()
+			if sent := time.Unix(timestamp, 0); sent.Before(now.Add(-replayWindow)) || sent.After(now.Add(replayWindow)) {
+				http.Error(w, "Unauthorized: webhook timestamp outside the replay window", http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+			mac.Write(body)
+			expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+			if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+				http.Error(w, "Unauthorized: invalid webhook signature", http.StatusUnauthorized)
+				return
+			}
+			if !firstUse(expected, now) {
+				http.Error(w, "Unauthorized: webhook already delivered", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// MaxFilenameLength is the maximum length of an attachment's file name in characters.
+const MaxFilenameLength = 255
+
+// Attachment is a file attached to a task. Its contents live in the blob store.
+type Attachment struct {
+	ID     int32 `json:"id"`
+	TaskID int32 `json:"task_id"`
+	// UserID is the uploader, whose quota the file counts against; nil once their account is deleted.
+	UserID   *uuid.UUID `json:"user_id"`
+	Filename string     `json:"filename"`
+	// ContentType is sniffed from the contents, not taken from the client.
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	BlobKey     string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AttachmentUpload is a resumable upload of an attachment sent in chunks.
+// Offset is the number of bytes received so far; the next chunk must start there.
+type AttachmentUpload struct {
+	ID        uuid.UUID `json:"id"`
+	TaskID    int32     `json:"task_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NormalizeFilename reduces a client supplied file name to its base name without
+// control characters and validates it.
+func NormalizeFilename(name string) (string, error) {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("%w: file name is required", ErrInvalidInput)
+	}
+	if len([]rune(name)) > MaxFilenameLength {
+		return "", fmt.Errorf("%w: file name must be at most %d characters", ErrInvalidInput, MaxFilenameLength)
+	}
+	return name, nil
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxCommentLength is the maximum length of a comment body in characters.
+const MaxCommentLength = 10000
+
+// TaskComment is a Markdown comment in the discussion of a task.
+type TaskComment struct {
+	ID     int32 `json:"id"`
+	TaskID int32 `json:"task_id"`
+	// UserID is the author, nil once the author's account is deleted.
+	UserID *uuid.UUID `json:"user_id"`
+	Body   string     `json:"body"`
+	// Mentions lists the users with access to the task that the body @mentions.
+	Mentions  []uuid.UUID `json:"mentions"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// EditedAt is set when the body was changed after posting.
+	EditedAt *time.Time `json:"edited_at"`
+}
+
+// Normalize trims the body and validates the comment.
+func (c *TaskComment) Normalize() error {
+	c.Body = strings.TrimSpace(c.Body)
+	if c.Body == "" {
+		return fmt.Errorf("%w: comment body is required", ErrInvalidInput)
+	}
+	if len([]rune(c.Body)) > MaxCommentLength {
+		return fmt.Errorf("%w: comment body must be at most %d characters", ErrInvalidInput, MaxCommentLength)
+	}
+	return nil
+}
+
+// TaskCommentRevision is a previous body of an edited comment.
+type TaskCommentRevision struct {
+	ID        int32     `json:"id"`
+	CommentID int32     `json:"comment_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Kinds of system events in a task's activity feed.
+const (
+	TaskActivityStatusChanged  = "status_changed"
+	TaskActivityDueDateChanged = "due_date_changed"
+)
+
+// TaskActivityEvent is a change to a task recorded for its activity feed.
+// Values are the old and new status, or RFC 3339 due dates with nil for no deadline.
+type TaskActivityEvent struct {
+	ID        int64      `json:"id"`
+	TaskID    int32      `json:"task_id"`
+	UserID    *uuid.UUID `json:"user_id"`
+	Kind      string     `json:"kind"`
+	OldValue  *string    `json:"old_value"`
+	NewValue  *string    `json:"new_value"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Types of the entries in a task's activity feed.
+const (
+	ActivityItemComment = "comment"
+	ActivityItemEvent   = "event"
+)
+
+// TaskActivityItem is an entry of a task's activity feed, holding either a comment or an event.
+type TaskActivityItem struct {
+	Type      string             `json:"type"`
+	CreatedAt time.Time          `json:"created_at"`
+	Comment   *TaskComment       `json:"comment,omitempty"`
+	Event     *TaskActivityEvent `json:"event,omitempty"`
+}
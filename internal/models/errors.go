@@ -0,0 +1,20 @@
+package models
+
+import "errors"
+
+var (
+	// ErrInvalidInput is wrapped by errors caused by invalid client data.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrAlreadyExists is returned when a unique constraint would be violated.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrPreconditionFailed is returned when a conditional request does not match the current version.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrForbidden is returned when the user's workspace role or task access does not allow the change.
+	ErrForbidden = errors.New("forbidden")
+	// ErrTooLarge is returned when an upload exceeds the file size limit.
+	ErrTooLarge = errors.New("file too large")
+	// ErrQuotaExceeded is returned when an upload would exceed the user's storage quota.
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
+	// ErrUnsupportedType is returned when the contents of an upload are not of an accepted type.
+	ErrUnsupportedType = errors.New("unsupported content type")
+)
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// ExportFormatVersion is increased whenever the layout of UserExport changes incompatibly.
+const ExportFormatVersion = 1
+
+// UserExport is the complete copy of a user's data handed out on request.
+type UserExport struct {
+	FormatVersion int                    `json:"format_version"`
+	ExportedAt    time.Time              `json:"exported_at"`
+	User          *User                  `json:"user"`
+	Settings      *UserSettings          `json:"settings"`
+	Workspaces    []*Workspace           `json:"workspaces"`
+	Projects      []*Project             `json:"projects"`
+	Labels        []*Label               `json:"labels"`
+	Tasks         []*Task                `json:"tasks"`
+	Comments      []*TaskComment         `json:"comments"`
+	Tokens        []*PersonalAccessToken `json:"tokens"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in a task's history.
+const (
+	TaskEventCreated  = "created"
+	TaskEventUpdated  = "updated"
+	TaskEventTrashed  = "trashed"
+	TaskEventRestored = "restored"
+	TaskEventDeleted  = "deleted"
+)
+
+// FieldChange is the value of a task field, as JSON, before and after a change.
+type FieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// TaskEvent is an entry of a task's audit history: one create, update, move to or from the trash
+// or permanent delete of the task.
+type TaskEvent struct {
+	ID     int64 `json:"id"`
+	TaskID int32 `json:"task_id"`
+	// ActorID is the user who made the change; nil for changes made by the system
+	// or by users whose account was deleted since.
+	ActorID *uuid.UUID `json:"actor_id"`
+	// RequestID is the ID of the HTTP request that made the change.
+	RequestID *string `json:"request_id"`
+	Action    string  `json:"action"`
+	// Version is the revision of the task the change produced, or the last one for deletes.
+	Version int32 `json:"version"`
+	// Changes maps the changed fields, named like in the task's JSON, to their old and new values.
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
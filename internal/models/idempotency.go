@@ -0,0 +1,28 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrIdempotencyKeyInUse is returned while another request with the same key is still being processed.
+	ErrIdempotencyKeyInUse = errors.New("idempotency key is in use by a request in progress")
+	// ErrIdempotencyKeyMismatch is returned when a key is reused for a different request.
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was used for a different request")
+)
+
+// IdempotencyRecord is the stored outcome of a request sent with an Idempotency-Key header.
+// StatusCode is nil while the first request is still being processed.
+type IdempotencyRecord struct {
+	UserID          uuid.UUID
+	Key             string
+	RequestHash     string
+	StatusCode      *int
+	ResponseHeaders map[string]string
+	ResponseBody    []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLabelColor  = "#808080"
+	MaxLabelNameLength = 64
+)
+
+var labelColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type Label struct {
+	ID          int32     `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	WorkspaceID int32     `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Normalize trims the label name, applies the default color and validates the label.
+func (l *Label) Normalize() error {
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		return fmt.Errorf("%w: label name is required", ErrInvalidInput)
+	}
+	if len([]rune(l.Name)) > MaxLabelNameLength {
+		return fmt.Errorf("%w: label name must be at most %d characters", ErrInvalidInput, MaxLabelNameLength)
+	}
+	if l.Color == "" {
+		l.Color = DefaultLabelColor
+	}
+	if !labelColorRe.MatchString(l.Color) {
+		return fmt.Errorf("%w: label color must be a hex color like #ff8800", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const InboxProjectName = "Inbox"
+
+// Modes for deleting a project that still contains tasks.
+const (
+	ProjectDeleteMoveToInbox = "move"
+	ProjectDeleteCascade     = "cascade"
+)
+
+type Project struct {
+	ID          int32      `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	WorkspaceID int32      `json:"workspace_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	IsInbox     bool       `json:"is_inbox"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Normalize trims the project name and validates the project.
+func (p *Project) Normalize() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("%w: project name is required", ErrInvalidInput)
+	}
+	if len([]rune(p.Name)) > 255 {
+		return fmt.Errorf("%w: project name must be at most 255 characters", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskSeries is the template of a recurring task. Each occurrence is a regular
+// task linked to the series, the next one is generated when the current one is completed.
+type TaskSeries struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	WorkspaceID    int32      `json:"workspace_id"`
+	RecurrenceRule string     `json:"recurrence_rule"`
+	DTStart        time.Time  `json:"dtstart"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	EndedAt        *time.Time `json:"ended_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultLocale is used for users that have not chosen a locale.
+const DefaultLocale = "en"
+
+// localeRegex accepts BCP 47 language tags such as "en", "de-AT" or "zh-Hant-TW".
+var localeRegex = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// UserSettings are the preferences of a user.
+type UserSettings struct {
+	// TimeZone is the IANA zone the user's days are computed in, stored with the user profile.
+	TimeZone string `json:"time_zone"`
+	Locale   string `json:"locale"`
+	// WeekStart is the first day of the week, 0 for Sunday through 6 for Saturday.
+	WeekStart int `json:"week_start"`
+	// DefaultProjectID receives new top-level tasks created without a project, nil for the Inbox.
+	DefaultProjectID *int32               `json:"default_project_id"`
+	Notifications    NotificationSettings `json:"notifications"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+}
+
+// NotificationSettings select which notifications a user receives.
+type NotificationSettings struct {
+	Email        bool `json:"email"`
+	DueReminders bool `json:"due_reminders"`
+	Mentions     bool `json:"mentions"`
+}
+
+// DefaultUserSettings returns the settings of a new user.
+func DefaultUserSettings() *UserSettings {
+	return &UserSettings{
+		TimeZone:      DefaultTimeZone,
+		Locale:        DefaultLocale,
+		WeekStart:     int(time.Monday),
+		Notifications: NotificationSettings{Email: true, DueReminders: true, Mentions: true},
+	}
+}
+
+// Normalize fills in defaults and validates the settings.
+func (s *UserSettings) Normalize() error {
+	if s.TimeZone == "" {
+		s.TimeZone = DefaultTimeZone
+	}
+	if _, err := LoadTimeZone(s.TimeZone); err != nil {
+		return err
+	}
+	if s.Locale == "" {
+		s.Locale = DefaultLocale
+	}
+	if !localeRegex.MatchString(s.Locale) || len(s.Locale) > 35 {
+		return fmt.Errorf("%w: locale must be a BCP 47 language tag", ErrInvalidInput)
+	}
+	if s.WeekStart < 0 || s.WeekStart > 6 {
+		return fmt.Errorf("%w: week_start must be between 0 (Sunday) and 6 (Saturday)", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Access levels of a user on a single task, from most to least privileged.
+// Owners may also delete and share the task, editors change it and viewers only read it.
+// View and edit are the permissions a task share can grant.
+const (
+	TaskAccessOwner = "owner"
+	TaskAccessEdit  = "edit"
+	TaskAccessView  = "view"
+)
+
+// taskAccessRanks orders the access levels; a missing level ranks lowest.
+var taskAccessRanks = map[string]int{
+	TaskAccessView:  1,
+	TaskAccessEdit:  2,
+	TaskAccessOwner: 3,
+}
+
+// TaskAccessAllows reports whether the access level includes the required one.
+func TaskAccessAllows(access, required string) bool {
+	return access != "" && taskAccessRanks[access] >= taskAccessRanks[required]
+}
+
+// TaskShare grants a user outside the task's workspace access to a single task.
+type TaskShare struct {
+	TaskID int32     `json:"task_id"`
+	UserID uuid.UUID `json:"user_id"`
+	// Email identifies the user to share with on create.
+	Email       string     `json:"email"`
+	DisplayName string     `json:"display_name"`
+	Permission  string     `json:"permission"`
+	SharedBy    *uuid.UUID `json:"shared_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Normalize canonicalizes the email, applies the default permission and validates the share.
+func (s *TaskShare) Normalize() error {
+	addr, err := mail.ParseAddress(strings.TrimSpace(s.Email))
+	if err != nil || addr.Name != "" {
+		return fmt.Errorf("%w: invalid email address", ErrInvalidInput)
+	}
+	s.Email = strings.ToLower(addr.Address)
+	if s.Permission == "" {
+		s.Permission = TaskAccessView
+	}
+	if s.Permission != TaskAccessView && s.Permission != TaskAccessEdit {
+		return fmt.Errorf("%w: permission must be one of view, edit", ErrInvalidInput)
+	}
+	return nil
+}
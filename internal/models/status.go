@@ -0,0 +1,71 @@
+package models
+
+import "errors"
+
+// Workflow states of a task. A task counts as completed only when it is done.
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusBlocked    = "blocked"
+	TaskStatusDone       = "done"
+	TaskStatusCancelled  = "cancelled"
+)
+
+// Priorities of a task, from lowest to highest.
+const (
+	TaskPriorityNone   = "none"
+	TaskPriorityLow    = "low"
+	TaskPriorityMedium = "medium"
+	TaskPriorityHigh   = "high"
+	TaskPriorityUrgent = "urgent"
+)
+
+// ErrInvalidTransition is returned when a task cannot move from its current status to the requested one.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// TaskPriorities lists the priorities in ascending order.
+var TaskPriorities = []string{TaskPriorityNone, TaskPriorityLow, TaskPriorityMedium, TaskPriorityHigh, TaskPriorityUrgent}
+
+// taskStatusTransitions holds the statuses reachable from each status.
+// Staying in the same status is always allowed.
+var taskStatusTransitions = map[string][]string{
+	TaskStatusTodo:       {TaskStatusInProgress, TaskStatusBlocked, TaskStatusDone, TaskStatusCancelled},
+	TaskStatusInProgress: {TaskStatusTodo, TaskStatusBlocked, TaskStatusDone, TaskStatusCancelled},
+	TaskStatusBlocked:    {TaskStatusTodo, TaskStatusInProgress, TaskStatusCancelled},
+	TaskStatusDone:       {TaskStatusTodo, TaskStatusInProgress},
+	TaskStatusCancelled:  {TaskStatusTodo},
+}
+
+// IsValidTaskStatus reports whether status is a known task status.
+func IsValidTaskStatus(status string) bool {
+	_, ok := taskStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionTaskStatus reports whether a task may move from one status to another.
+func CanTransitionTaskStatus(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, s := range taskStatusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskPriorityRank returns the position of priority in TaskPriorities, or -1 when it is unknown.
+func TaskPriorityRank(priority string) int {
+	for i, p := range TaskPriorities {
+		if p == priority {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsValidTaskPriority reports whether priority is a known task priority.
+func IsValidTaskPriority(priority string) bool {
+	return TaskPriorityRank(priority) >= 0
+}
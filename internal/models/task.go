@@ -1,18 +1,277 @@
 package models
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Task struct {
-	ID          int32     `json:"id"`
-	UserID      uuid.UUID `json:"user_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	DueDate     time.Time `json:"due_date"`
-	Completed   bool      `json:"completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID     int32     `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	// WorkspaceID is the workspace the task belongs to. It defaults to the project's
+	// workspace, or the creator's personal workspace, and cannot change afterwards.
+	WorkspaceID int32 `json:"workspace_id"`
+	// AssigneeID is the user the task is handed to, who may edit it even outside the workspace.
+	// It is set on create and changed through the task's assignee endpoint.
+	AssigneeID  *uuid.UUID `json:"assignee_id"`
+	ProjectID   *int32     `json:"project_id"`
+	ParentID    *int32     `json:"parent_id"`
+	SeriesID    *uuid.UUID `json:"series_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	// DueDate is nil for tasks without a deadline. For all-day tasks it is midnight UTC of the due day.
+	DueDate *time.Time `json:"due_date"`
+	// DueAllDay marks DueDate as a calendar day rather than a point in time.
+	DueAllDay bool `json:"due_all_day"`
+	// Status is the workflow state of the task, see the TaskStatus constants.
+	Status   string `json:"status"`
+	Priority string `json:"priority"`
+	// Completed mirrors Status == done and is kept for clients predating the status workflow.
+	// On write it is only used when no status is given.
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// Version is incremented on every change of the task row and backs its ETag.
+	Version int32 `json:"version"`
+	// DeletedAt is set while the task is in the trash. Such tombstones are only returned
+	// by the trash and to sync clients listing with IncludeDeleted.
+	DeletedAt *time.Time `json:"deleted_at"`
+	Labels    []*Label   `json:"labels"`
+	// Subtasks summarizes the completion of direct children, nil for leaf tasks.
+	Subtasks *SubtaskProgress `json:"subtasks,omitempty"`
+	// Children is only filled when a task tree is requested.
+	Children []*Task `json:"children,omitempty"`
+	// RecurrenceRule is the RFC 5545 RRULE of the task's series, nil when the task does not repeat.
+	// On update a nil value keeps the rule and an empty one stops the series.
+	RecurrenceRule *string `json:"recurrence_rule"`
+	// LabelIDs replaces the task's labels on create/update when set.
+	// A nil slice leaves the labels unchanged, an empty one detaches all of them.
+	LabelIDs []int32 `json:"label_ids,omitempty"`
+}
+
+// NormalizeDueDate moves the due date of an all-day task to midnight UTC of its calendar day,
+// taken in the offset the client sent it with.
+func (t *Task) NormalizeDueDate() error {
+	if !t.DueAllDay || t.DueDate == nil {
+		if t.DueAllDay {
+			return fmt.Errorf("%w: an all-day task needs a due date", ErrInvalidInput)
+		}
+		return nil
+	}
+	y, m, d := t.DueDate.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	t.DueDate = &day
+	return nil
+}
+
+// SubtaskProgress is the completion roll-up of a task's direct children.
+type SubtaskProgress struct {
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Summary   string `json:"summary"`
+}
+
+// NewSubtaskProgress builds the roll-up, e.g. "3/5 subtasks done".
+func NewSubtaskProgress(total, completed int) *SubtaskProgress {
+	return &SubtaskProgress{
+		Total:     total,
+		Completed: completed,
+		Summary:   fmt.Sprintf("%d/%d subtasks done", completed, total),
+	}
+}
+
+// Scopes of an update to an occurrence of a recurring task.
+const (
+	TaskScopeThis   = "this"
+	TaskScopeSeries = "series"
+)
+
+// TaskUpdateOptions tune how UpdateTask applies a change.
+type TaskUpdateOptions struct {
+	// CompleteSubtasks marks all descendants completed when the task is completed.
+	CompleteSubtasks bool
+	// Scope selects whether title and description changes of a recurring task
+	// apply to this occurrence only or to the whole series.
+	Scope string
+	// IfMatch lists the versions the client expects the task to have.
+	// Nil skips the check, otherwise a mismatch fails with ErrPreconditionFailed.
+	IfMatch []int32
+}
+
+// Sort fields accepted by TaskListOptions.
+const (
+	TaskSortCreatedAt = "created_at"
+	TaskSortUpdatedAt = "updated_at"
+	TaskSortDueDate   = "due_date"
+	TaskSortTitle     = "title"
+	TaskSortPriority  = "priority"
+	TaskSortID        = "id"
+)
+
+// Due date filters accepted by TaskListOptions. Days are taken in the user's time zone.
+const (
+	TaskDueToday   = "today"
+	TaskDueOverdue = "overdue"
+	TaskDueNone    = "none"
+)
+
+// Relations of listed tasks to the user. The default lists the tasks of the user's workspaces.
+const (
+	TaskRelationAssigned = "assigned"
+	TaskRelationShared   = "shared"
+)
+
+const (
+	DefaultTaskListLimit = 50
+	MaxTaskListLimit     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TaskListOptions holds the filters, ordering and page position for listing tasks.
+// Nil filter fields are not applied. Labels restricts the result to tasks
+// carrying all of the named labels, Statuses and Priorities to tasks matching any of the values.
+type TaskListOptions struct {
+	// Relation selects the tasks to list by how they reach the user, see the TaskRelation constants.
+	Relation    string
+	WorkspaceID *int32
+	ProjectID   *int32
+	Completed   *bool
+	Statuses    []string
+	Priorities  []string
+	Due         string
+	// Location is the time zone the Due filter is evaluated in, filled in by the service.
+	Location      *time.Location
+	DueBefore     *time.Time
+	DueAfter      *time.Time
+	CreatedBefore *time.Time
+	CreatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	// IncludeDeleted adds the tombstones of tasks in the trash, so that sync clients listing
+	// the tasks updated after their last sync learn about deletions.
+	IncludeDeleted bool
+	Labels         []string
+	SortBy         string
+	SortDesc       bool
+	Limit          int
+	Cursor         *TaskCursor
+}
+
+// TaskCursor is the keyset position after the last task of a page.
+// Value holds the sort column value of that task, ID breaks ties.
+type TaskCursor struct {
+	SortBy   string `json:"s"`
+	SortDesc bool   `json:"d"`
+	Value    string `json:"v"`
+	ID       int32  `json:"id"`
+}
+
+// TaskPage is a single page of tasks returned by a list request.
+type TaskPage struct {
+	Tasks      []*Task `json:"tasks"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// IsValidTaskSortField reports whether field can be used to order tasks.
+func IsValidTaskSortField(field string) bool {
+	switch field {
+	case TaskSortCreatedAt, TaskSortUpdatedAt, TaskSortDueDate, TaskSortTitle, TaskSortPriority, TaskSortID:
+		return true
+	}
+	return false
+}
+
+// Normalize fills in defaults and validates the options.
+func (o *TaskListOptions) Normalize() error {
+	if o.SortBy == "" {
+		o.SortBy = TaskSortCreatedAt
+	}
+	if !IsValidTaskSortField(o.SortBy) {
+		return fmt.Errorf("unsupported sort field %q", o.SortBy)
+	}
+	switch o.Due {
+	case "", TaskDueToday, TaskDueOverdue, TaskDueNone:
+	default:
+		return fmt.Errorf("unsupported due filter %q", o.Due)
+	}
+	for _, status := range o.Statuses {
+		if !IsValidTaskStatus(status) {
+			return fmt.Errorf("unsupported status %q", status)
+		}
+	}
+	for _, priority := range o.Priorities {
+		if !IsValidTaskPriority(priority) {
+			return fmt.Errorf("unsupported priority %q", priority)
+		}
+	}
+	if o.Limit <= 0 {
+		o.Limit = DefaultTaskListLimit
+	}
+	if o.Limit > MaxTaskListLimit {
+		o.Limit = MaxTaskListLimit
+	}
+	if o.Cursor != nil && (o.Cursor.SortBy != o.SortBy || o.Cursor.SortDesc != o.SortDesc) {
+		return fmt.Errorf("%w: cursor does not match the requested sort order", ErrInvalidCursor)
+	}
+	return nil
+}
+
+// CursorAfter builds the cursor pointing right after the given task.
+func (o *TaskListOptions) CursorAfter(task *Task) *TaskCursor {
+	c := &TaskCursor{SortBy: o.SortBy, SortDesc: o.SortDesc, ID: task.ID}
+	switch o.SortBy {
+	case TaskSortCreatedAt:
+		c.Value = task.CreatedAt.Format(time.RFC3339Nano)
+	case TaskSortUpdatedAt:
+		c.Value = task.UpdatedAt.Format(time.RFC3339Nano)
+	case TaskSortDueDate:
+		c.Value = "infinity" // tasks without a due date sort last
+		if task.DueDate != nil {
+			c.Value = task.DueDate.Format(time.RFC3339Nano)
+		}
+	case TaskSortTitle:
+		c.Value = task.Title
+	case TaskSortPriority:
+		c.Value = task.Priority
+	}
+	return c
+}
+
+// Encode returns the opaque string representation of the cursor.
+func (c *TaskCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeTaskCursor parses a cursor previously produced by TaskCursor.Encode.
+func DecodeTaskCursor(s string) (*TaskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c TaskCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if !IsValidTaskSortField(c.SortBy) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// TaskSearchResult is a task matched by a full-text search together with its
+// relevance rank and highlighted fragments of the matched fields. The highlights are HTML:
+// the text is escaped and matches are wrapped in <mark> tags.
+type TaskSearchResult struct {
+	Task                 *Task   `json:"task"`
+	Rank                 float32 `json:"rank"`
+	TitleHighlight       string  `json:"title_highlight"`
+	DescriptionHighlight string  `json:"description_highlight"`
 }
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTaskCursorRoundTrip(t *testing.T) {
+	due := time.Date(2026, time.March, 29, 1, 30, 0, 123, time.UTC)
+	tests := []struct {
+		name      string
+		opts      TaskListOptions
+		task      *Task
+		wantValue string
+	}{
+		{name: "created at", opts: TaskListOptions{SortBy: TaskSortCreatedAt, SortDesc: true},
+			task: &Task{ID: 3, CreatedAt: due}, wantValue: "2026-03-29T01:30:00.000000123Z"},
+		{name: "due date", opts: TaskListOptions{SortBy: TaskSortDueDate},
+			task: &Task{ID: 3, DueDate: &due}, wantValue: "2026-03-29T01:30:00.000000123Z"},
+		{name: "no due date", opts: TaskListOptions{SortBy: TaskSortDueDate},
+			task: &Task{ID: 3}, wantValue: "infinity"},
+		{name: "title", opts: TaskListOptions{SortBy: TaskSortTitle},
+			task: &Task{ID: 3, Title: "Ünïcode & \"quotes\""}, wantValue: "Ünïcode & \"quotes\""},
+		{name: "priority", opts: TaskListOptions{SortBy: TaskSortPriority, SortDesc: true},
+			task: &Task{ID: 3, Priority: "high"}, wantValue: "high"},
+		{name: "id", opts: TaskListOptions{SortBy: TaskSortID},
+			task: &Task{ID: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := DecodeTaskCursor(tt.opts.CursorAfter(tt.task).Encode())
+			if err != nil {
+				t.Fatalf("DecodeTaskCursor: %v", err)
+			}
+			if c.SortBy != tt.opts.SortBy || c.SortDesc != tt.opts.SortDesc || c.ID != tt.task.ID || c.Value != tt.wantValue {
+				t.Errorf("cursor = %+v, want sort %s desc %v after task %d at %q", c, tt.opts.SortBy, tt.opts.SortDesc, tt.task.ID, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestDecodeTaskCursorRejects(t *testing.T) {
+	for name, s := range map[string]string{
+		"not base64":         "%%%",
+		"not json":           base64.RawURLEncoding.EncodeToString([]byte("nope")),
+		"unknown sort field": base64.RawURLEncoding.EncodeToString([]byte(`{"s":"password","id":1}`)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeTaskCursor(s); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestTaskListOptionsRejectsForeignCursor(t *testing.T) {
+	cursor := (&TaskListOptions{SortBy: TaskSortTitle}).CursorAfter(&Task{ID: 1, Title: "a"})
+	for name, opts := range map[string]TaskListOptions{
+		"other field": {SortBy: TaskSortPriority, Cursor: cursor},
+		"other order": {SortBy: TaskSortTitle, SortDesc: true, Cursor: cursor},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := opts.Normalize(); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	opts := TaskListOptions{SortBy: TaskSortTitle, Cursor: cursor}
+	if err := opts.Normalize(); err != nil {
+		t.Errorf("Normalize with a matching cursor: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes that restrict what a token may do. Tasks scopes also cover labels, projects, workspaces
+// and the profile and settings of the user.
+// They are shared by personal access tokens and OAuth2 access tokens.
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+)
+
+// KnownScopes lists all scopes a token can be granted.
+var KnownScopes = []string{ScopeTasksRead, ScopeTasksWrite}
+
+const (
+	// PersonalAccessTokenPrefix starts every personal access token, so they are easy to recognize and scan for.
+	PersonalAccessTokenPrefix = "tmpat_"
+	// personalAccessTokenDisplayLength is the number of leading characters kept to identify a token.
+	personalAccessTokenDisplayLength = 12
+	MaxTokenNameLength               = 100
+)
+
+// PersonalAccessToken lets scripts call the API on behalf of a user.
+// Only a hash of the token is stored, the plain token is returned once on creation.
+type PersonalAccessToken struct {
+	ID     int32     `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	// Prefix is the start of the token, shown to tell tokens apart.
+	Prefix string `json:"prefix"`
+	// Scopes limit the token, an empty list grants all scopes.
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Token is the plain token, only set in the response to its creation.
+	Token string `json:"token,omitempty"`
+}
+
+// Normalize trims the token name, deduplicates the scopes and validates the token.
+func (t *PersonalAccessToken) Normalize() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("%w: token name is required", ErrInvalidInput)
+	}
+	if len([]rune(t.Name)) > MaxTokenNameLength {
+		return fmt.Errorf("%w: token name must be at most %d characters", ErrInvalidInput, MaxTokenNameLength)
+	}
+	if t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidInput)
+	}
+
+	scopes := []string{}
+	for _, scope := range t.Scopes {
+		if !slices.Contains(KnownScopes, scope) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidInput, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	t.Scopes = scopes
+	return nil
+}
+
+// GrantedScopes returns the scopes the token grants, expanding an empty list to all scopes.
+func (t *PersonalAccessToken) GrantedScopes() []string {
+	if len(t.Scopes) == 0 {
+		return slices.Clone(KnownScopes)
+	}
+	return t.Scopes
+}
+
+// NewPersonalAccessTokenSecret generates a random token and returns it with its display prefix.
+func NewPersonalAccessTokenSecret() (token, prefix string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = PersonalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, token[:personalAccessTokenDisplayLength], nil
+}
+
+// HashToken returns the hex SHA-256 hash a token is stored and looked up by.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,82 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"github.com/google/uuid"
 )
 
 type User struct {
 	ID       uuid.UUID `json:"id"`
 	KratosID string    `json:"kratos_id"`
+	// Email mirrors the email trait of the Kratos identity and is not editable here.
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	// TimeZone is the IANA name of the zone the user's days are computed in.
+	TimeZone  string    `json:"time_zone"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IdentityTraits are the Kratos identity traits copied into the user profile.
+type IdentityTraits struct {
+	Email string `json:"email"`
+}
+
+// ParseIdentityTraits extracts the identity traits from the raw traits of a Kratos identity.
+// It reports false when they carry no email.
+func ParseIdentityTraits(raw interface{}) (IdentityTraits, bool) {
+	var traits IdentityTraits
+	if raw == nil {
+		return traits, false
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return traits, false
+	}
+	if err := json.Unmarshal(data, &traits); err != nil || traits.Email == "" {
+		return traits, false
+	}
+	return traits, true
+}
+
+const (
+	// DefaultTimeZone is used for users that have not chosen a time zone.
+	DefaultTimeZone = "UTC"
+	// MaxDisplayNameLength limits the display name, in characters.
+	MaxDisplayNameLength = 100
+)
+
+// LoadTimeZone resolves an IANA time zone name such as "Europe/Berlin".
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("%w: time zone must be an IANA name", ErrInvalidInput)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidInput, name)
+	}
+	return loc, nil
+}
+
+// DefaultDisplayName derives a display name from an email address, e.g. "jane" for jane@example.com.
+func DefaultDisplayName(email string) string {
+	name, _, _ := strings.Cut(email, "@")
+	if utf8.RuneCountInString(name) > MaxDisplayNameLength {
+		name = string([]rune(name)[:MaxDisplayNameLength])
+	}
+	return name
+}
+
+// NormalizeDisplayName trims the display name and validates its length.
+func NormalizeDisplayName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > MaxDisplayNameLength {
+		return "", fmt.Errorf("%w: display name must be at most %d characters", ErrInvalidInput, MaxDisplayNameLength)
+	}
+	return name, nil
 }
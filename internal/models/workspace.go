@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles of workspace members, from most to least privileged.
+// Owners manage the workspace and its owners, admins manage members and invitations,
+// members edit the content and viewers only read it.
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+	WorkspaceRoleViewer = "viewer"
+)
+
+const (
+	PersonalWorkspaceName  = "Personal"
+	MaxWorkspaceNameLength = 255
+	// WorkspaceInvitationTTL is how long an invitation can be accepted.
+	WorkspaceInvitationTTL = 7 * 24 * time.Hour
+)
+
+// IsValidWorkspaceRole reports whether role is a known workspace role.
+func IsValidWorkspaceRole(role string) bool {
+	switch role {
+	case WorkspaceRoleOwner, WorkspaceRoleAdmin, WorkspaceRoleMember, WorkspaceRoleViewer:
+		return true
+	}
+	return false
+}
+
+// CanEditWorkspaceContent reports whether the role may create and change tasks, projects and labels.
+func CanEditWorkspaceContent(role string) bool {
+	return role == WorkspaceRoleOwner || role == WorkspaceRoleAdmin || role == WorkspaceRoleMember
+}
+
+// CanManageWorkspace reports whether the role may rename the workspace and manage members and invitations.
+func CanManageWorkspace(role string) bool {
+	return role == WorkspaceRoleOwner || role == WorkspaceRoleAdmin
+}
+
+// Workspace groups tasks, projects and labels shared by its members.
+// Every user has a personal workspace where they are the only member.
+type Workspace struct {
+	ID         int32      `json:"id"`
+	Name       string     `json:"name"`
+	IsPersonal bool       `json:"is_personal"`
+	CreatedBy  *uuid.UUID `json:"created_by"`
+	// Role is the role of the requesting user in the workspace.
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize trims the workspace name and validates the workspace.
+func (w *Workspace) Normalize() error {
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" {
+		return fmt.Errorf("%w: workspace name is required", ErrInvalidInput)
+	}
+	if len([]rune(w.Name)) > MaxWorkspaceNameLength {
+		return fmt.Errorf("%w: workspace name must be at most %d characters", ErrInvalidInput, MaxWorkspaceNameLength)
+	}
+	return nil
+}
+
+// WorkspaceMember is a user's membership in a workspace.
+type WorkspaceMember struct {
+	WorkspaceID int32     `json:"workspace_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WorkspaceInvitation invites the owner of an email address to join a workspace.
+// It is accepted or declined by the user signed in with that email.
+type WorkspaceInvitation struct {
+	ID          int32 `json:"id"`
+	WorkspaceID int32 `json:"workspace_id"`
+	// WorkspaceName is filled in for invitations listed to the invited user.
+	WorkspaceName string     `json:"workspace_name,omitempty"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	InvitedBy     *uuid.UUID `json:"invited_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	AcceptedAt    *time.Time `json:"accepted_at"`
+	DeclinedAt    *time.Time `json:"declined_at"`
+}
+
+// Normalize canonicalizes the email, applies the default role and validates the invitation.
+// Ownership cannot be granted by invitation.
+func (i *WorkspaceInvitation) Normalize() error {
+	addr, err := mail.ParseAddress(strings.TrimSpace(i.Email))
+	if err != nil || addr.Name != "" {
+		return fmt.Errorf("%w: invalid email address", ErrInvalidInput)
+	}
+	i.Email = strings.ToLower(addr.Address)
+	if i.Role == "" {
+		i.Role = WorkspaceRoleMember
+	}
+	if !IsValidWorkspaceRole(i.Role) || i.Role == WorkspaceRoleOwner {
+		return fmt.Errorf("%w: role must be one of admin, member, viewer", ErrInvalidInput)
+	}
+	return nil
+}
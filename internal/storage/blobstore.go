@@ -0,0 +1,43 @@
+// Package storage keeps the contents of attachments in a blob store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/HellUpa/taskmanager/internal/config"
+)
+
+// ErrNotFound is returned when a blob does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// Backends of the blob store selectable in the configuration.
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// BlobStore stores blobs under opaque keys chosen by the caller.
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing any existing blob.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns a reader over the blob that can seek, so that ranges can be served.
+	// It returns ErrNotFound when the blob does not exist.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the blob. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New creates the blob store selected by the configuration.
+func New(cfg config.AttachmentsConfig) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", BackendLocal:
+		return NewLocalStore(cfg.LocalPath)
+	case BackendS3:
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob store backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps blobs as files below a root directory.
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates a store in the root directory, creating it when missing.
+func NewLocalStore(root string) (*LocalStore, error) {
+	if root == "" {
+		return nil, errors.New("local blob store needs a path")
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+// path maps a key to its file, rejecting keys that would escape the root.
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || filepath.IsAbs(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the blob to a temporary file and renames it into place, so readers never see partial blobs.
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if n != size {
+		return fmt.Errorf("blob size mismatch: wrote %d of %d bytes", n, size)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	return nil
+}
+
+// Open opens the blob file.
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return f, nil
+}
+
+// Delete removes the blob file.
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
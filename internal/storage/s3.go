@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/config"
+)
+
+// unsignedPayload lets object bodies stream without hashing them up front.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Store keeps blobs as objects in a bucket of an S3-compatible service, such as AWS S3 or MinIO.
+// Requests are signed with AWS Signature Version 4.
+type S3Store struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+// NewS3Store creates a store for the configured bucket.
+func NewS3Store(cfg config.S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 blob store needs an endpoint and a bucket")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Store{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		pathStyle: cfg.PathStyle,
+		client:    &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// objectURL returns the URL of the object stored under key.
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	return &u
+}
+
+// do signs and sends a request for the object stored under key.
+func (s *S3Store) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign adds the Signature Version 4 authorization to the request.
+func (s *S3Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// responseError turns an unexpected response into an error, including the start of its body.
+func responseError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s failed with status %d: %s", op, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// Put uploads the blob as an object.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, r, size, header)
+	if err != nil {
+		return fmt.Errorf("failed to upload blob: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError("put", resp)
+	}
+	return nil
+}
+
+// Open looks up the size of the object and returns a reader fetching it with range requests.
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat blob: %w", err)
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, responseError("head", resp)
+	}
+	return &s3Object{ctx: ctx, store: s, key: key, size: resp.ContentLength}, nil
+}
+
+// Delete removes the object.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError("delete", resp)
+	}
+	return nil
+}
+
+// s3Object reads an object from the current offset on, reopening the download after a seek.
+type s3Object struct {
+	ctx    context.Context
+	store  *S3Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		header := http.Header{}
+		header.Set("Range", "bytes="+strconv.FormatInt(o.offset, 10)+"-")
+		resp, err := o.store.do(o.ctx, http.MethodGet, o.key, nil, 0, header)
+		if err != nil {
+			return 0, fmt.Errorf("failed to download blob: %w", err)
+		}
+		if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return 0, responseError("get", resp)
+		}
+		o.body = resp.Body
+	}
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	var next int64
+	switch whence {
+	case io.SeekStart:
+		next = offset
+	case io.SeekCurrent:
+		next = o.offset + offset
+	case io.SeekEnd:
+		next = o.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if next < 0 {
+		return 0, errors.New("negative position")
+	}
+	if next != o.offset && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.offset = next
+	return next, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HellUpa/taskmanager/internal/config"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testBucket    = "attachments"
+	testRegion    = "eu-central-1"
+)
+
+// fakeS3 is a stand-in for an S3 service keeping objects in memory. It verifies the Signature
+// Version 4 of every request independently of S3Store and records the requests it served.
+type fakeS3 struct {
+	t         *testing.T
+	pathStyle bool
+
+	mu       sync.Mutex
+	objects  map[string][]byte
+	types    map[string]string
+	requests []*http.Request
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySigV4(r, testSecretKey, testRegion); err != nil {
+		f.t.Logf("rejected %s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if f.pathStyle {
+		var ok bool
+		if key, ok = strings.CutPrefix(key, testBucket+"/"); !ok {
+			http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+			return
+		}
+	} else if !strings.HasPrefix(r.Host, testBucket+".") {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r)
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(body)) != r.ContentLength {
+			http.Error(w, "<Error><Code>IncompleteBody</Code></Error>", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodHead, http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		status := http.StatusOK
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			if err != nil || start >= len(body) {
+				http.Error(w, "<Error><Code>InvalidRange</Code></Error>", http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(body)-1)+"/"+strconv.Itoa(len(body)))
+			body = body[start:]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// served returns the method and Range header of the requests served so far.
+func (f *fakeS3) served() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, r := range f.requests {
+		out = append(out, strings.TrimSpace(r.Method+" "+r.Header.Get("Range")))
+	}
+	return out
+}
+
+// verifySigV4 checks the AWS Signature Version 4 of a request signed with an unsigned payload.
+func verifySigV4(r *http.Request, secretKey, region string) error {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return errors.New("missing AWS4-HMAC-SHA256 authorization")
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		fields[name] = value
+	}
+
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[0] != testAccessKey || credential[2] != region || credential[3] != "s3" || credential[4] != "aws4_request" {
+		return errors.New("malformed credential " + fields["Credential"])
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil || credential[1] != amzDate[:8] {
+		return errors.New("X-Amz-Date does not match the credential scope")
+	}
+	if time.Since(signedAt).Abs() > 15*time.Minute {
+		return errors.New("request signed outside of the allowed clock skew")
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return errors.New("missing X-Amz-Content-Sha256")
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	for _, required := range []string{"host", "x-amz-date", "x-amz-content-sha256"} {
+		if !strings.Contains(";"+fields["SignedHeaders"]+";", ";"+required+";") {
+			return errors.New(required + " is not signed")
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join(credential[1:], "/")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	signingKey := mac(mac(mac(mac([]byte("AWS4"+secretKey), credential[1]), region), "s3"), "aws4_request")
+	want := hex.EncodeToString(mac(signingKey, stringToSign))
+	if !hmac.Equal([]byte(want), []byte(fields["Signature"])) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// newTestS3 starts a fake S3 service and returns a store for it using secretKey.
+func newTestS3(t *testing.T, pathStyle bool, secretKey string) (*S3Store, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{t: t, pathStyle: pathStyle, objects: map[string][]byte{}, types: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store, err := NewS3Store(config.S3Config{
+		Endpoint:  srv.URL,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+		PathStyle: pathStyle,
+		Timeout:   5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	// Virtual-hosted requests go to <bucket>.127.0.0.1, which has to reach the test server.
+	addr := srv.Listener.Addr().String()
+	store.client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	return store, fake
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	for _, pathStyle := range []bool{true, false} {
+		t.Run("pathStyle="+strconv.FormatBool(pathStyle), func(t *testing.T) {
+			ctx := context.Background()
+			store, fake := newTestS3(t, pathStyle, testSecretKey)
+			const key = "tasks/42/report.txt"
+			content := "hello, attachments"
+
+			if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if got := string(fake.objects[key]); got != content {
+				t.Fatalf("stored %q, want %q", got, content)
+			}
+			if got := fake.types[key]; got != "text/plain" {
+				t.Errorf("stored content type %q, want text/plain", got)
+			}
+			if put := fake.requests[0]; !strings.Contains(put.Header.Get("Authorization"), "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date,") {
+				t.Errorf("content type is not signed: %s", put.Header.Get("Authorization"))
+			}
+
+			obj, err := store.Open(ctx, key)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer obj.Close()
+			if size, _ := obj.Seek(0, io.SeekEnd); size != int64(len(content)) {
+				t.Fatalf("size = %d, want %d", size, len(content))
+			}
+			if _, err := obj.Seek(7, io.SeekStart); err != nil {
+				t.Fatalf("Seek: %v", err)
+			}
+			tail, err := io.ReadAll(obj)
+			if err != nil || string(tail) != content[7:] {
+				t.Fatalf("read after seek = %q, %v; want %q", tail, err, content[7:])
+			}
+			if _, err := obj.Seek(0, io.SeekStart); err != nil {
+				t.Fatalf("Seek: %v", err)
+			}
+			head := make([]byte, 5)
+			if _, err := io.ReadFull(obj, head); err != nil || string(head) != content[:5] {
+				t.Fatalf("read from start = %q, %v; want %q", head, err, content[:5])
+			}
+
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Open after Delete = %v, want ErrNotFound", err)
+			}
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete of a missing object: %v", err)
+			}
+
+			want := []string{"PUT", "HEAD", "GET bytes=7-", "GET bytes=0-", "DELETE", "HEAD", "DELETE"}
+			if got := fake.served(); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("served %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestS3StoreWrongSecret(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newTestS3(t, true, "not-the-secret")
+
+	err := store.Put(ctx, "k", strings.NewReader("x"), 1, "")
+	if err == nil || !strings.Contains(err.Error(), "status 403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("Put with a wrong secret = %v, want a 403 SignatureDoesNotMatch error", err)
+	}
+	if _, err := store.Open(ctx, "k"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open with a wrong secret = %v, want a 403 error", err)
+	}
+	if err := store.Delete(ctx, "k"); err == nil {
+		t.Fatal("Delete with a wrong secret succeeded")
+	}
+	if len(fake.objects) != 0 {
+		t.Fatal("unsigned request changed the bucket")
+	}
+}
+
+func TestNewS3StoreConfig(t *testing.T) {
+	if _, err := NewS3Store(config.S3Config{Bucket: testBucket}); err == nil {
+		t.Error("store without endpoint was created")
+	}
+	if _, err := NewS3Store(config.S3Config{Endpoint: "http://minio:9000"}); err == nil {
+		t.Error("store without bucket was created")
+	}
+	store, err := NewS3Store(config.S3Config{Endpoint: "http://minio:9000/", Bucket: testBucket, PathStyle: true})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	if store.region != "us-east-1" {
+		t.Errorf("region = %q, want the us-east-1 default", store.region)
+	}
+	if got := store.objectURL("a/b").String(); got != "http://minio:9000/attachments/a/b" {
+		t.Errorf("path-style URL = %q", got)
+	}
+}